@@ -1,75 +1,88 @@
 // notes.go
-// Markdown Notes CLI (single-file)
-// Build: go build -o notes notes.go
+// Markdown Notes CLI
+// Build: go build -tags sqlite_fts5 -o notes .  (or `make build`)
+// The sqlite_fts5 tag is required: mattn/go-sqlite3 does not compile FTS5
+// support without it, and NOTES_BACKEND=sqlite / `notes reindex` fail at
+// runtime ("no such module: fts5") on a binary built without it.
+//
+// Flags (before the subcommand):
+//   --notebook <name>, -N <name> - use the named notebook from
+//                                   ~/.config/notes/config.toml instead of
+//                                   the default one
 //
 // Commands:
+//   notes init [--encrypt]        - create notes_db/ (optionally age/passphrase-encrypted)
+//   notes unlock                 - cache an encrypted notebook's identity for NOTES_AGENT_TTL
+//   notes lock                   - drop the cached identity
 //   notes add <title>            - opens $EDITOR (or vim) to write markdown body
 //   notes list                   - list saved notes (id, title, tags)
 //   notes view <id>              - print note (title + body)
-//   notes search <query>         - search title/body/tags (case-insensitive)
+//   notes search <query>         - search title/body/tags, supports tag:<t> and
+//                                   created:<from>..<to> (YYYY-MM-DD) filters
 //   notes tag <id> <tag> [tag2]  - add one or more tags to a note
-//   notes export <id> <file>     - export note to a simple HTML file
+//   notes export <id> <file>     - export note (--format html|pdf|pandoc|epub|json, --template T)
+//   notes export --all --out dir - export the whole notebook as a static site
+//   notes reindex [--dir=d] [--force] - rebuild the SQLite index from notes_db/*.json
+//                                   (refuses to touch an encrypted notebook in place)
+//   notes lsp                    - run an LSP server over stdio for editor integration
+//   notes links <id>             - show outgoing [[wiki-links]] from a note
+//   notes backlinks <id>         - show notes linking to a note
+//   notes graph                  - emit the note graph (--format dot|json|mermaid)
+//   notes lint                   - report broken [[wiki-links]]
 //   notes help                   - show usage
 //
 // Data: one JSON file per note in ./notes_db/ (note files named 0001.json, 0002.json, ...)
+// by default; a notebook's GroupConfig (see config.go) can rename the pattern,
+// extension and id format. Search additionally runs through a SQLite+FTS5 index
+// (notes_db/notes.db) when NOTES_BACKEND=sqlite is set; otherwise it falls back
+// to a plain substring scan over the JSON files.
+//
+// After `notes init --encrypt`, every note file is an age ciphertext instead
+// of plain JSON (see crypto.go); `notes unlock` caches the decryption key in
+// a background agent for NOTES_AGENT_TTL (default 15m) so other commands
+// don't re-prompt for the passphrase.
+//
+// Which notebook a command runs against is resolved once in main (see
+// resolveNotebook in config.go) from --notebook/-N, NOTES_NOTEBOOK,
+// ./.notes/config.toml, or ~/.config/notes/config.toml's `default`, and
+// cached in activeNotebook for the rest of the process.
 
 package main
 
 import (
-	"encoding/json"
 	"errors"
 	"fmt"
 	"os"
 	"os/exec"
 	"path/filepath"
-	"sort"
 	"strconv"
 	"strings"
 	"time"
 )
 
-const dbDir = "notes_db"
-
-type Note struct {
-	ID      int       `json:"id"`
-	Title   string    `json:"title"`
-	Body    string    `json:"body"`
-	Tags    []string  `json:"tags"`
-	Created time.Time `json:"created"`
-}
-
-func ensureDir() error {
-	return os.MkdirAll(dbDir, 0o755)
-}
-
-func notePath(id int) string {
-	return filepath.Join(dbDir, fmt.Sprintf("%04d.json", id))
-}
+// activeNotebook is resolved once in main() and used by every command
+// that used to hang its storage directly off a hardcoded notes_db/.
+var activeNotebook *Notebook
 
-func loadAll() ([]Note, error) {
-	if err := ensureDir(); err != nil {
-		return nil, err
-	}
-	entries, err := os.ReadDir(dbDir)
-	if err != nil {
+// openStorage picks the active backend for activeNotebook. NOTES_BACKEND=sqlite
+// opens <dir>/notes.db (building it from the JSON files on first use);
+// anything else uses the plain JSON-per-file store. The SQLite backend
+// keeps title/body/tags in cleartext columns for FTS5, so it refuses to
+// open against an encrypted notebook rather than leak plaintext next to
+// the ciphertext notes — `notes reindex` after `notes unlock` rebuilds
+// it on demand instead.
+func openStorage() (Storage, error) {
+	dir := activeNotebook.Dir
+	if err := os.MkdirAll(dir, 0o755); err != nil {
 		return nil, err
 	}
-	notes := make([]Note, 0, len(entries))
-	for _, e := range entries {
-		if e.IsDir() || !strings.HasSuffix(e.Name(), ".json") {
-			continue
-		}
-		b, err := os.ReadFile(filepath.Join(dbDir, e.Name()))
-		if err != nil {
-			continue
-		}
-		var n Note
-		if err := json.Unmarshal(b, &n); err == nil {
-			notes = append(notes, n)
+	if os.Getenv("NOTES_BACKEND") == "sqlite" {
+		if notebookEncrypted(dir) {
+			return nil, errors.New("NOTES_BACKEND=sqlite stores notes in cleartext; run `notes unlock && notes reindex` into a throwaway copy, or unset NOTES_BACKEND")
 		}
+		return newSQLiteStore(filepath.Join(dir, "notes.db"))
 	}
-	sort.Slice(notes, func(i, j int) bool { return notes[i].ID < notes[j].ID })
-	return notes, nil
+	return newJSONStore(dir, activeNotebook.Group)
 }
 
 func nextID(notes []Note) int {
@@ -82,23 +95,11 @@ func nextID(notes []Note) int {
 	return max + 1
 }
 
-func saveNote(n Note) error {
-	if err := ensureDir(); err != nil {
-		return err
-	}
-	b, err := json.MarshalIndent(n, "", "  ")
-	if err != nil {
-		return err
-	}
-	tmp := notePath(n.ID) + ".tmp"
-	if err := os.WriteFile(tmp, b, 0o644); err != nil {
-		return err
-	}
-	return os.Rename(tmp, notePath(n.ID))
-}
-
 func openEditor(initial string) (string, error) {
-	editor := os.Getenv("EDITOR")
+	editor := activeNotebook.Editor
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
 	if editor == "" {
 		editor = "vim"
 	}
@@ -134,7 +135,12 @@ func cmdAdd(args []string) error {
 		return errors.New("usage: add <title>")
 	}
 	title := strings.Join(args, " ")
-	notes, err := loadAll()
+	store, err := openStorage()
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+	notes, err := store.All()
 	if err != nil {
 		return err
 	}
@@ -143,14 +149,15 @@ func cmdAdd(args []string) error {
 	if err != nil {
 		return fmt.Errorf("editor error: %w", err)
 	}
+	tags := append([]string{}, activeNotebook.Tags...)
 	n := Note{
 		ID:      id,
 		Title:   title,
 		Body:    body,
-		Tags:    []string{},
+		Tags:    tags,
 		Created: time.Now().UTC(),
 	}
-	if err := saveNote(n); err != nil {
+	if err := store.Save(n); err != nil {
 		return err
 	}
 	fmt.Printf("Saved note #%d\n", id)
@@ -158,7 +165,12 @@ func cmdAdd(args []string) error {
 }
 
 func cmdList(args []string) error {
-	notes, err := loadAll()
+	store, err := openStorage()
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+	notes, err := store.All()
 	if err != nil {
 		return err
 	}
@@ -173,18 +185,6 @@ func cmdList(args []string) error {
 	return nil
 }
 
-func loadNote(id int) (*Note, error) {
-	b, err := os.ReadFile(notePath(id))
-	if err != nil {
-		return nil, err
-	}
-	var n Note
-	if err := json.Unmarshal(b, &n); err != nil {
-		return nil, err
-	}
-	return &n, nil
-}
-
 func cmdView(args []string) error {
 	if len(args) == 0 {
 		return errors.New("usage: view <id>")
@@ -193,7 +193,12 @@ func cmdView(args []string) error {
 	if err != nil {
 		return err
 	}
-	n, err := loadNote(id)
+	store, err := openStorage()
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+	n, err := store.Load(id)
 	if err != nil {
 		return err
 	}
@@ -201,26 +206,64 @@ func cmdView(args []string) error {
 	return nil
 }
 
+// parseSearchArgs splits `tag:work foo created:2024-01-01..2024-06-30` into
+// a free-text query and structured filters.
+func parseSearchArgs(args []string) (string, SearchFilters, error) {
+	var terms []string
+	var f SearchFilters
+	for _, a := range args {
+		switch {
+		case strings.HasPrefix(a, "tag:"):
+			f.Tag = strings.TrimPrefix(a, "tag:")
+		case strings.HasPrefix(a, "created:"):
+			rng := strings.TrimPrefix(a, "created:")
+			parts := strings.SplitN(rng, "..", 2)
+			from, err := time.Parse("2006-01-02", parts[0])
+			if err != nil {
+				return "", f, fmt.Errorf("invalid created: filter %q: %w", a, err)
+			}
+			f.CreatedAfter = from
+			if len(parts) == 2 && parts[1] != "" {
+				to, err := time.Parse("2006-01-02", parts[1])
+				if err != nil {
+					return "", f, fmt.Errorf("invalid created: filter %q: %w", a, err)
+				}
+				f.CreatedBefore = to.Add(24 * time.Hour)
+			}
+		default:
+			terms = append(terms, a)
+		}
+	}
+	return strings.Join(terms, " "), f, nil
+}
+
 func cmdSearch(args []string) error {
 	if len(args) == 0 {
-		return errors.New("usage: search <query>")
+		return errors.New("usage: search [tag:<t>] [created:<from>..<to>] <query>")
 	}
-	q := strings.ToLower(strings.Join(args, " "))
-	notes, err := loadAll()
+	query, filters, err := parseSearchArgs(args)
 	if err != nil {
 		return err
 	}
-	found := 0
-	for _, n := range notes {
-		if strings.Contains(strings.ToLower(n.Title), q) ||
-			strings.Contains(strings.ToLower(n.Body), q) ||
-			strings.Contains(strings.ToLower(strings.Join(n.Tags, ",")), q) {
-			fmt.Printf("%3d  %s\n", n.ID, n.Title)
-			found++
-		}
+	store, err := openStorage()
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+	hits, err := store.Search(query, filters)
+	if err != nil {
+		return err
 	}
-	if found == 0 {
+	if len(hits) == 0 {
 		fmt.Println("No matches.")
+		return nil
+	}
+	for _, h := range hits {
+		if h.Snippet != "" {
+			fmt.Printf("%3d  %s\n     %s\n", h.Note.ID, h.Note.Title, h.Snippet)
+		} else {
+			fmt.Printf("%3d  %s\n", h.Note.ID, h.Note.Title)
+		}
 	}
 	return nil
 }
@@ -233,7 +276,12 @@ func cmdTag(args []string) error {
 	if err != nil {
 		return err
 	}
-	n, err := loadNote(id)
+	store, err := openStorage()
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+	n, err := store.Load(id)
 	if err != nil {
 		return err
 	}
@@ -255,82 +303,144 @@ func cmdTag(args []string) error {
 		}
 	}
 	n.Tags = res
-	if err := saveNote(*n); err != nil {
+	if err := store.Save(*n); err != nil {
 		return err
 	}
 	fmt.Printf("Updated tags for #%d\n", id)
 	return nil
 }
 
-func htmlEscape(s string) string {
-	repl := strings.NewReplacer(
-		"&", "&amp;",
-		"<", "&lt;",
-		">", "&gt;",
-		`"`, "&quot;",
-	)
-	return repl.Replace(s)
+// cmdInit sets up the active notebook's directory. Plain `notes init`
+// just creates it (openStorage would do this lazily anyway); `--encrypt`
+// additionally generates the age identity used to encrypt every note.
+func cmdInit(args []string) error {
+	for _, a := range args {
+		if a == "--encrypt" {
+			return cmdInitEncrypt()
+		}
+	}
+	if err := os.MkdirAll(activeNotebook.Dir, 0o755); err != nil {
+		return err
+	}
+	fmt.Printf("Initialized notebook in %s\n", activeNotebook.Dir)
+	return nil
 }
 
-func cmdExport(args []string) error {
-	if len(args) < 2 {
-		return errors.New("usage: export <id> <file.html>")
+// cmdReindex rebuilds a sqliteStore from the notebook's JSON files.
+// sqliteStore keeps title/body/tags in cleartext columns for FTS5 (see
+// openStorage), so reindexing an encrypted notebook in place would write
+// a world/group-readable notes.db full of plaintext right next to the
+// age-encrypted notes. Refuse unless the caller passes --dir=<elsewhere>
+// to reindex into a throwaway copy, or --force to acknowledge the risk.
+func cmdReindex(args []string) error {
+	dir := activeNotebook.Dir
+	outDir := dir
+	force := false
+	for _, a := range args {
+		if v, ok := strings.CutPrefix(a, "--dir="); ok {
+			outDir = v
+			continue
+		}
+		if a == "--force" {
+			force = true
+		}
 	}
-	id, err := strconv.Atoi(args[0])
+	if notebookEncrypted(dir) && outDir == dir && !force {
+		return errors.New("notebook is encrypted; reindexing in place would write plaintext notes.db next to the ciphertext notes — pass --dir=<throwaway copy> or --force")
+	}
+	js, err := newJSONStore(dir, activeNotebook.Group)
 	if err != nil {
 		return err
 	}
-	out := args[1]
-	n, err := loadNote(id)
-	if err != nil {
+	if err := os.MkdirAll(outDir, 0o755); err != nil {
 		return err
 	}
-	var b strings.Builder
-	b.WriteString("<!doctype html>\n<html>\n<head>\n<meta charset=\"utf-8\">\n")
-	b.WriteString("<title>" + htmlEscape(n.Title) + "</title>\n</head>\n<body>\n")
-	b.WriteString("<h1>" + htmlEscape(n.Title) + "</h1>\n")
-	// Very small markdown-ish -> HTML: handle lines starting with "# " as header, otherwise paragraphs.
-	lines := strings.Split(n.Body, "\n")
-	for _, L := range lines {
-		if strings.HasPrefix(L, "# ") {
-			b.WriteString("<h2>" + htmlEscape(strings.TrimSpace(strings.TrimPrefix(L, "# "))) + "</h2>\n")
-			continue
-		}
-		if strings.TrimSpace(L) == "" {
-			continue
-		}
-		b.WriteString("<p>" + htmlEscape(L) + "</p>\n")
+	sq, err := newSQLiteStore(filepath.Join(outDir, "notes.db"))
+	if err != nil {
+		return err
 	}
-	b.WriteString("</body>\n</html>\n")
-	if err := os.WriteFile(out, []byte(b.String()), 0o644); err != nil {
+	defer sq.Close()
+	n, err := reindexFromJSON(js, sq)
+	if err != nil {
 		return err
 	}
-	fmt.Printf("Exported note #%d to %s\n", id, out)
+	fmt.Printf("Reindexed %d note(s) into %s\n", n, filepath.Join(outDir, "notes.db"))
 	return nil
 }
 
 func cmdHelp() {
 	prog := filepath.Base(os.Args[0])
 	fmt.Printf("%s - markdown notes CLI\n\n", prog)
+	fmt.Println("Flags (before the subcommand):")
+	fmt.Println("  --notebook, -N name   Use a named notebook from ~/.config/notes/config.toml")
+	fmt.Println()
 	fmt.Println("Commands:")
+	fmt.Println("  init [--encrypt]      Create notes_db/ (optionally age/passphrase-encrypted)")
+	fmt.Println("  unlock                Cache an encrypted notebook's identity for NOTES_AGENT_TTL")
+	fmt.Println("  lock                  Drop the cached identity")
 	fmt.Println("  add <title>           Add a note (opens $EDITOR or vim)")
 	fmt.Println("  list                  List notes")
 	fmt.Println("  view <id>             View note")
-	fmt.Println("  search <query>        Search title/body/tags")
+	fmt.Println("  search <query>        Search title/body/tags (tag:, created: filters)")
 	fmt.Println("  tag <id> tag1 ...     Add tags to a note")
-	fmt.Println("  export <id> file.html Export to simple HTML")
+	fmt.Println("  export <id> file      Export a note (--format, --template)")
+	fmt.Println("  export --all --out d  Export the notebook as a static site")
+	fmt.Println("  reindex [--dir=d]     Rebuild the SQLite index from notes_db/*.json")
+	fmt.Println("          [--force]     (refuses to touch an encrypted notebook in place)")
+	fmt.Println("  lsp                   Run a Language Server Protocol server over stdio")
+	fmt.Println("  links <id>            Show outgoing [[wiki-links]] from a note")
+	fmt.Println("  backlinks <id>        Show notes linking to a note")
+	fmt.Println("  graph                 Emit the note graph (--format dot|json|mermaid, --out f)")
+	fmt.Println("  lint                  Report broken [[wiki-links]]")
 	fmt.Println("  help                  Show this help")
 }
 
+// takeNotebookFlag strips a leading --notebook/-N <name> (or
+// --notebook=<name>) from args, which must come before the subcommand,
+// and returns the notebook name plus the remaining args.
+func takeNotebookFlag(args []string) (string, []string, error) {
+	if len(args) == 0 {
+		return "", args, nil
+	}
+	a := args[0]
+	if v, ok := strings.CutPrefix(a, "--notebook="); ok {
+		return v, args[1:], nil
+	}
+	if a == "--notebook" || a == "-N" {
+		if len(args) < 2 {
+			return "", nil, fmt.Errorf("%s requires a value", a)
+		}
+		return args[1], args[2:], nil
+	}
+	return "", args, nil
+}
+
 func main() {
-	if len(os.Args) < 2 {
+	notebookName, rest, err := takeNotebookFlag(os.Args[1:])
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		os.Exit(1)
+	}
+	if len(rest) < 1 {
 		cmdHelp()
 		return
 	}
-	cmd := os.Args[1]
-	args := os.Args[2:]
-	var err error
+	activeNotebook, err = resolveNotebook(notebookName)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		os.Exit(1)
+	}
+	cmd := rest[0]
+	args := rest[1:]
 	switch cmd {
+	case "init":
+		err = cmdInit(args)
+	case "unlock":
+		err = cmdUnlock(args)
+	case "lock":
+		err = cmdLockNotebook(args)
+	case "agent-serve":
+		err = runAgentServe(args)
 	case "add":
 		err = cmdAdd(args)
 	case "list":
@@ -343,6 +453,18 @@ func main() {
 		err = cmdTag(args)
 	case "export":
 		err = cmdExport(args)
+	case "reindex":
+		err = cmdReindex(args)
+	case "lsp":
+		err = runLSP(os.Stdin, os.Stdout, activeNotebook)
+	case "links":
+		err = cmdLinks(args)
+	case "backlinks":
+		err = cmdBacklinks(args)
+	case "graph":
+		err = cmdGraph(args)
+	case "lint":
+		err = cmdLint(args)
 	case "help", "-h", "--help":
 		cmdHelp()
 		return
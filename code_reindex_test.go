@@ -0,0 +1,47 @@
+//go:build sqlite_fts5
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"filippo.io/age"
+)
+
+// TestCmdReindexRefusesEncryptedNotebookInPlace guards the notes_db/notes.db
+// sqliteStore (cleartext title/body/tags, see openStorage) from ever being
+// written next to an encrypted notebook's ciphertext .json files without
+// the caller explicitly asking for it via --dir or --force.
+func TestCmdReindexRefusesEncryptedNotebookInPlace(t *testing.T) {
+	dir := t.TempDir()
+	identity, err := age.GenerateX25519Identity()
+	if err != nil {
+		t.Fatalf("GenerateX25519Identity: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, recipientsFile), []byte(identity.Recipient().String()+"\n"), 0o644); err != nil {
+		t.Fatalf("write recipients file: %v", err)
+	}
+	origNotebook := activeNotebook
+	activeNotebook = &Notebook{Dir: dir}
+	defer func() { activeNotebook = origNotebook }()
+
+	if err := cmdReindex(nil); err == nil {
+		t.Fatal("cmdReindex must refuse to reindex an encrypted notebook in place")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "notes.db")); err == nil {
+		t.Fatal("cmdReindex must not create notes.db next to an encrypted notebook's ciphertext")
+	}
+
+	throwaway := t.TempDir()
+	if err := cmdReindex([]string{"--dir=" + throwaway}); err != nil {
+		t.Fatalf("cmdReindex --dir=<throwaway>: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(throwaway, "notes.db")); err != nil {
+		t.Fatalf("expected notes.db in the throwaway dir: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "notes.db")); err == nil {
+		t.Fatal("notes.db must not have been written into the encrypted notebook dir")
+	}
+}
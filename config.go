@@ -0,0 +1,268 @@
+// config.go
+// Layered notebook configuration. A global ~/.config/notes/config.toml
+// registers named notebooks (so `notes --notebook work list` works from
+// any directory); an optional per-directory .notes/config.toml lets a
+// bare `notes` invocation configure its own notebook without a global
+// registry entry; NOTES_* env vars override either for one-off runs.
+// resolveNotebook applies them in that order and falls back to the
+// historical notes_db/ layout so existing notebooks keep working
+// unconfigured.
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pelletier/go-toml/v2"
+)
+
+// defaultDir is the notebook directory used when nothing configures one,
+// matching the layout every notebook used before config.toml existed.
+const defaultDir = "notes_db"
+
+// GroupConfig controls how jsonStore names note files on disk. Pattern
+// is a template containing the {id} and {ext} placeholders; idFormat is
+// the fmt verb used to render {id} (zero-padded by default so filenames
+// sort the same lexically and numerically).
+type GroupConfig struct {
+	Pattern   string `toml:"pattern"`
+	IDFormat  string `toml:"id_format"`
+	Extension string `toml:"extension"`
+}
+
+func (g GroupConfig) filename(id int) string {
+	pattern := g.Pattern
+	if pattern == "" {
+		pattern = "{id}.{ext}"
+	}
+	idFormat := g.IDFormat
+	if idFormat == "" {
+		idFormat = "%04d"
+	}
+	ext := g.Extension
+	if ext == "" {
+		ext = "json"
+	}
+	name := strings.ReplaceAll(pattern, "{id}", fmt.Sprintf(idFormat, id))
+	return strings.ReplaceAll(name, "{ext}", ext)
+}
+
+func (g GroupConfig) extOrDefault() string {
+	if g.Extension == "" {
+		return "json"
+	}
+	return g.Extension
+}
+
+// validate rejects a pattern/extension that would let a note file land
+// outside dir, e.g. pattern = "../../../tmp/pwned-{id}.{ext}" in a
+// notebook's own .notes/config.toml (notebookConfigAt, trusted by
+// resolveNotebook for any bare `notes` invocation in that directory).
+// Checking g.filename(0) covers both Pattern and Extension, since
+// filename already folds both into the same path.
+func (g GroupConfig) validate(dir string) error {
+	candidate := filepath.Join(dir, g.filename(0))
+	rel, err := filepath.Rel(dir, candidate)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return fmt.Errorf("notebook group pattern %q would write outside %s", g.Pattern, dir)
+	}
+	return nil
+}
+
+// Notebook is one notebook's resolved settings: where it stores notes,
+// and the defaults CLI commands fall back to when a flag is omitted.
+// Name is filled in by resolveNotebook, not read from TOML.
+type Notebook struct {
+	Name     string      `toml:"-"`
+	Dir      string      `toml:"dir"`
+	Editor   string      `toml:"editor"`
+	Tags     []string    `toml:"tags"`
+	Template string      `toml:"template"`
+	Group    GroupConfig `toml:"group"`
+}
+
+// globalConfig is the shape of ~/.config/notes/config.toml.
+type globalConfig struct {
+	Default   string               `toml:"default"`
+	Notebooks map[string]*Notebook `toml:"notebooks"`
+}
+
+func globalConfigPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "notes", "config.toml")
+}
+
+func loadGlobalConfig() (*globalConfig, error) {
+	path := globalConfigPath()
+	if path == "" {
+		return &globalConfig{}, nil
+	}
+	b, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return &globalConfig{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var cfg globalConfig
+	if err := toml.Unmarshal(b, &cfg); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	return &cfg, nil
+}
+
+// notebookConfigAt reads dir/.notes/config.toml, if present. A relative
+// `dir =` inside it is resolved against dir itself, so a notebook's own
+// config doesn't need to know where it was checked out.
+func notebookConfigAt(dir string) (*Notebook, error) {
+	path := filepath.Join(dir, ".notes", "config.toml")
+	b, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var nb Notebook
+	if err := toml.Unmarshal(b, &nb); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	if nb.Dir == "" {
+		nb.Dir = dir
+	} else if !filepath.IsAbs(nb.Dir) {
+		nb.Dir = filepath.Join(dir, nb.Dir)
+	}
+	return &nb, nil
+}
+
+// resolveNotebook picks the active notebook for this invocation. An
+// explicit --notebook/-N flag or NOTES_NOTEBOOK env var names a notebook
+// from the global config; otherwise a ./.notes/config.toml wins;
+// otherwise the global config's own `default`; otherwise the historical
+// notes_db/ layout. NOTES_DIR always overrides the resolved dir, for
+// one-off invocations against a notebook that isn't configured anywhere.
+func resolveNotebook(flagName string) (*Notebook, error) {
+	cfg, err := loadGlobalConfig()
+	if err != nil {
+		return nil, err
+	}
+	name := flagName
+	if name == "" {
+		name = os.Getenv("NOTES_NOTEBOOK")
+	}
+	var nb *Notebook
+	if name != "" {
+		found, ok := cfg.Notebooks[name]
+		if !ok {
+			return nil, fmt.Errorf("no notebook %q in %s", name, globalConfigPath())
+		}
+		nb = found
+	} else {
+		local, err := notebookConfigAt(".")
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case local != nil:
+			nb = local
+		case cfg.Default != "":
+			found, ok := cfg.Notebooks[cfg.Default]
+			if !ok {
+				return nil, fmt.Errorf("default notebook %q not found in %s", cfg.Default, globalConfigPath())
+			}
+			name = cfg.Default
+			nb = found
+		default:
+			nb = &Notebook{}
+		}
+	}
+
+	resolved := *nb
+	resolved.Name = name
+	if resolved.Dir == "" {
+		resolved.Dir = defaultDir
+	}
+	if v := os.Getenv("NOTES_DIR"); v != "" {
+		resolved.Dir = v
+	}
+	if resolved.Editor == "" {
+		resolved.Editor = os.Getenv("NOTES_EDITOR")
+	}
+	return &resolved, nil
+}
+
+// Workspace holds several open notebooks at once, keyed by resolved
+// directory, so a single `notes lsp` session can serve the multiple
+// notebook roots a client may have open without reopening storage on
+// every request.
+type Workspace struct {
+	notebooks map[string]Storage
+}
+
+func NewWorkspace() *Workspace {
+	return &Workspace{notebooks: map[string]Storage{}}
+}
+
+// Open returns the Storage for nb, opening and caching it by directory
+// on first use.
+func (w *Workspace) Open(nb *Notebook) (Storage, error) {
+	abs, err := filepath.Abs(nb.Dir)
+	if err != nil {
+		abs = nb.Dir
+	}
+	if s, ok := w.notebooks[abs]; ok {
+		return s, nil
+	}
+	if err := os.MkdirAll(nb.Dir, 0o755); err != nil {
+		return nil, err
+	}
+	s, err := newJSONStore(nb.Dir, nb.Group)
+	if err != nil {
+		return nil, err
+	}
+	w.notebooks[abs] = s
+	return s, nil
+}
+
+// Get returns the store already open for nb's directory, or nil.
+func (w *Workspace) Get(nb *Notebook) Storage {
+	abs, err := filepath.Abs(nb.Dir)
+	if err != nil {
+		abs = nb.Dir
+	}
+	return w.notebooks[abs]
+}
+
+// For returns the open store whose notebook directory contains path,
+// falling back to the single open notebook when there is only one.
+func (w *Workspace) For(path string) Storage {
+	var best Storage
+	bestLen := -1
+	for dir, s := range w.notebooks {
+		if (path == dir || strings.HasPrefix(path, dir+string(filepath.Separator))) && len(dir) > bestLen {
+			best, bestLen = s, len(dir)
+		}
+	}
+	if best == nil && len(w.notebooks) == 1 {
+		for _, s := range w.notebooks {
+			best = s
+		}
+	}
+	return best
+}
+
+func (w *Workspace) Close() error {
+	var firstErr error
+	for _, s := range w.notebooks {
+		if err := s.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestNotebookConfigAtResolvesRelativeDir(t *testing.T) {
+	root := t.TempDir()
+	notesDir := filepath.Join(root, ".notes")
+	if err := os.MkdirAll(notesDir, 0o755); err != nil {
+		t.Fatalf("MkdirAll: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(notesDir, "config.toml"), []byte(`dir = "data"`+"\n"), 0o644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	nb, err := notebookConfigAt(root)
+	if err != nil {
+		t.Fatalf("notebookConfigAt: %v", err)
+	}
+	if nb == nil {
+		t.Fatal("notebookConfigAt returned nil for a dir with .notes/config.toml")
+	}
+	want := filepath.Join(root, "data")
+	if nb.Dir != want {
+		t.Errorf("nb.Dir = %q, want %q", nb.Dir, want)
+	}
+}
+
+func TestNotebookConfigAtAbsent(t *testing.T) {
+	nb, err := notebookConfigAt(t.TempDir())
+	if err != nil {
+		t.Fatalf("notebookConfigAt: %v", err)
+	}
+	if nb != nil {
+		t.Errorf("notebookConfigAt = %+v, want nil when no .notes/config.toml exists", nb)
+	}
+}
+
+// TestResolveNotebookPrefersLocalConfigOverGlobalDefault covers the
+// layering resolveNotebook documents: a ./.notes/config.toml wins over
+// the global config's `default` notebook when no --notebook/-N flag or
+// NOTES_NOTEBOOK env var names one explicitly.
+func TestResolveNotebookPrefersLocalConfigOverGlobalDefault(t *testing.T) {
+	xdg := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", xdg)
+	t.Setenv("NOTES_NOTEBOOK", "")
+	t.Setenv("NOTES_DIR", "")
+	if err := os.MkdirAll(filepath.Join(xdg, "notes"), 0o755); err != nil {
+		t.Fatalf("MkdirAll: %v", err)
+	}
+	globalToml := "default = \"work\"\n[notebooks.work]\ndir = \"" + filepath.Join(xdg, "work-dir") + "\"\n"
+	if err := os.WriteFile(filepath.Join(xdg, "notes", "config.toml"), []byte(globalToml), 0o644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	cwd := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(cwd, ".notes"), 0o755); err != nil {
+		t.Fatalf("MkdirAll: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(cwd, ".notes", "config.toml"), []byte(`dir = "local-dir"`+"\n"), 0o644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	origWD, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Getwd: %v", err)
+	}
+	if err := os.Chdir(cwd); err != nil {
+		t.Fatalf("Chdir: %v", err)
+	}
+	defer os.Chdir(origWD)
+
+	nb, err := resolveNotebook("")
+	if err != nil {
+		t.Fatalf("resolveNotebook: %v", err)
+	}
+	if nb.Dir != "local-dir" {
+		t.Errorf("resolveNotebook().Dir = %q, want local notebook dir %q (global default must not win)", nb.Dir, "local-dir")
+	}
+}
+
+func TestResolveNotebookNotesDirOverridesEverything(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv("NOTES_NOTEBOOK", "")
+	override := filepath.Join(t.TempDir(), "override")
+	t.Setenv("NOTES_DIR", override)
+
+	cwd := t.TempDir()
+	origWD, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Getwd: %v", err)
+	}
+	if err := os.Chdir(cwd); err != nil {
+		t.Fatalf("Chdir: %v", err)
+	}
+	defer os.Chdir(origWD)
+
+	nb, err := resolveNotebook("")
+	if err != nil {
+		t.Fatalf("resolveNotebook: %v", err)
+	}
+	if nb.Dir != override {
+		t.Errorf("resolveNotebook().Dir = %q, want NOTES_DIR override %q", nb.Dir, override)
+	}
+}
+
+// TestWorkspaceForRequiresPathSeparatorBoundary guards against a sibling
+// directory that merely shares a dir's name as a prefix (e.g.
+// "notes-archive" next to "notes") being wrongly claimed as belonging to
+// that notebook.
+func TestWorkspaceForRequiresPathSeparatorBoundary(t *testing.T) {
+	root := t.TempDir()
+	dir := filepath.Join(root, "notes")
+	siblingDir := filepath.Join(root, "notes-archive")
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatalf("MkdirAll: %v", err)
+	}
+	if err := os.MkdirAll(siblingDir, 0o755); err != nil {
+		t.Fatalf("MkdirAll: %v", err)
+	}
+
+	w := NewWorkspace()
+	store, err := w.Open(&Notebook{Dir: dir})
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	// Open a second notebook so For can't fall back to "only one open".
+	if _, err := w.Open(&Notebook{Dir: t.TempDir()}); err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+
+	if got := w.For(filepath.Join(siblingDir, "note.md")); got == store {
+		t.Error("Workspace.For matched a sibling dir sharing a name prefix")
+	}
+	if got := w.For(filepath.Join(dir, "note.md")); got != store {
+		t.Error("Workspace.For failed to match a file actually inside the notebook dir")
+	}
+}
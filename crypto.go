@@ -0,0 +1,378 @@
+// crypto.go
+// Optional encryption at rest: when a notebook was created with `notes
+// init --encrypt`, every note file under its directory is an age
+// ciphertext rather than plain JSON. jsonStore stays oblivious to this — it just
+// hands Save/Load bytes through a noteCipher, so the existing .tmp+Rename
+// atomic-write path applies to ciphertext exactly as it did to plaintext.
+//
+// The passphrase-derived identity is cached by a tiny local agent
+// (analogous to ssh-agent) so `notes unlock` only has to prompt once per
+// NOTES_AGENT_TTL window instead of on every command.
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"filippo.io/age"
+	"golang.org/x/term"
+)
+
+const (
+	recipientsFile   = ".age-recipients"
+	identityFile     = ".age-identity"
+	defaultAgentTTL  = 15 * time.Minute
+	scryptWorkFactor = 18
+)
+
+// noteCipher encrypts/decrypts the bytes jsonStore writes to/reads from
+// disk. A notebook with no recipientsFile uses a no-op cipher.
+type noteCipher interface {
+	Encrypt(plaintext []byte) ([]byte, error)
+	Decrypt(ciphertext []byte) ([]byte, error)
+}
+
+type plainCipher struct{}
+
+func (plainCipher) Encrypt(b []byte) ([]byte, error) { return b, nil }
+func (plainCipher) Decrypt(b []byte) ([]byte, error) { return b, nil }
+
+type ageCipher struct {
+	recipient age.Recipient
+	dir       string // notebook dir, to locate identityFile when the agent is cold
+}
+
+func (c *ageCipher) Encrypt(plaintext []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	w, err := age.Encrypt(&buf, c.recipient)
+	if err != nil {
+		return nil, fmt.Errorf("age encrypt: %w", err)
+	}
+	if _, err := w.Write(plaintext); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (c *ageCipher) Decrypt(ciphertext []byte) ([]byte, error) {
+	id, err := unlockedIdentity(c.dir)
+	if err != nil {
+		return nil, err
+	}
+	r, err := age.Decrypt(bytes.NewReader(ciphertext), id)
+	if err != nil {
+		return nil, fmt.Errorf("age decrypt: %w", err)
+	}
+	return io.ReadAll(r)
+}
+
+// notebookEncrypted reports whether dir was `init --encrypt`-ed.
+func notebookEncrypted(dir string) bool {
+	_, err := os.Stat(filepath.Join(dir, recipientsFile))
+	return err == nil
+}
+
+// notebookCipher returns the cipher openStorage should use for dir: a
+// plainCipher if the notebook was never `init --encrypt`-ed, else an
+// ageCipher targeting the notebook's recipient.
+func notebookCipher(dir string) (noteCipher, error) {
+	recipientPath := filepath.Join(dir, recipientsFile)
+	b, err := os.ReadFile(recipientPath)
+	if errors.Is(err, os.ErrNotExist) {
+		return plainCipher{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	recipient, err := age.ParseX25519Recipient(strings.TrimSpace(string(b)))
+	if err != nil {
+		return nil, fmt.Errorf("parse %s: %w", recipientPath, err)
+	}
+	return &ageCipher{recipient: recipient, dir: dir}, nil
+}
+
+// cmdInitEncrypt generates an X25519 identity, writes its public recipient
+// to <dir>/.age-recipients (used to encrypt new notes) and its private
+// key to <dir>/.age-identity, itself passphrase-protected with scrypt
+// so the private key is never stored in the clear.
+func cmdInitEncrypt() error {
+	if err := os.MkdirAll(activeNotebook.Dir, 0o755); err != nil {
+		return err
+	}
+	identity, err := age.GenerateX25519Identity()
+	if err != nil {
+		return err
+	}
+	passphrase, err := promptPassphrase("New notebook passphrase: ")
+	if err != nil {
+		return err
+	}
+	confirm, err := promptPassphrase("Confirm passphrase: ")
+	if err != nil {
+		return err
+	}
+	if passphrase != confirm {
+		return errors.New("passphrases did not match")
+	}
+	scryptRecipient, err := age.NewScryptRecipient(passphrase)
+	if err != nil {
+		return err
+	}
+	scryptRecipient.SetWorkFactor(scryptWorkFactor)
+
+	var buf bytes.Buffer
+	w, err := age.Encrypt(&buf, scryptRecipient)
+	if err != nil {
+		return err
+	}
+	if _, err := io.WriteString(w, identity.String()); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(activeNotebook.Dir, identityFile), buf.Bytes(), 0o600); err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(activeNotebook.Dir, recipientsFile), []byte(identity.Recipient().String()+"\n"), 0o644); err != nil {
+		return err
+	}
+	fmt.Println("Notebook encryption enabled. Run `notes unlock` before using add/view/search.")
+	return nil
+}
+
+func promptPassphrase(prompt string) (string, error) {
+	fmt.Fprint(os.Stderr, prompt)
+	b, err := term.ReadPassword(int(os.Stdin.Fd()))
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// --- identity agent -------------------------------------------------------
+//
+// `notes unlock` decrypts the notebook's .age-identity once and hands the
+// plaintext identity to a background agent process listening on a unix
+// socket, analogous to ssh-agent. Later commands in the same TTL window
+// fetch the identity from the socket instead of re-prompting.
+
+func agentSockPath(dir string) string {
+	if v := os.Getenv("NOTES_AGENT_SOCK"); v != "" {
+		return v
+	}
+	abs, _ := filepath.Abs(dir)
+	return filepath.Join(agentSockDir(), fmt.Sprintf("notes-agent-%d-%x.sock", os.Getuid(), hashPath(abs)))
+}
+
+// agentSockDir returns a private, 0700 directory under os.TempDir() to
+// hold agent sockets, creating it if needed. A socket placed directly in
+// the shared, world-writable TempDir() would have its access control
+// depend entirely on the process umask (0000 on some container base
+// images); a dedicated 0700 directory keeps other local users out the
+// same way ssh-agent's mkdtemp'd socket directory does, regardless of
+// umask.
+func agentSockDir() string {
+	dir := filepath.Join(os.TempDir(), fmt.Sprintf("notes-agent-%d", os.Getuid()))
+	if err := os.MkdirAll(dir, 0o700); err == nil {
+		_ = os.Chmod(dir, 0o700)
+	}
+	return dir
+}
+
+func hashPath(p string) uint32 {
+	var h uint32 = 2166136261
+	for i := 0; i < len(p); i++ {
+		h ^= uint32(p[i])
+		h *= 16777619
+	}
+	return h
+}
+
+func agentTTL() time.Duration {
+	if v := os.Getenv("NOTES_AGENT_TTL"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil {
+			return d
+		}
+	}
+	return defaultAgentTTL
+}
+
+// unlockedIdentity fetches the cached identity from a running agent, or
+// falls back to prompting for the passphrase directly (without caching)
+// if no agent is reachable.
+func unlockedIdentity(dir string) (age.Identity, error) {
+	sock := agentSockPath(dir)
+	if conn, err := net.Dial("unix", sock); err == nil {
+		defer conn.Close()
+		fmt.Fprintln(conn, "GET")
+		line, err := bufio.NewReader(conn).ReadString('\n')
+		if err == nil && strings.HasPrefix(line, "OK ") {
+			return age.ParseX25519Identity(strings.TrimSpace(strings.TrimPrefix(line, "OK ")))
+		}
+	}
+	return decryptIdentityFile(dir)
+}
+
+func decryptIdentityFile(dir string) (age.Identity, error) {
+	b, err := os.ReadFile(filepath.Join(dir, identityFile))
+	if err != nil {
+		return nil, err
+	}
+	passphrase, err := promptPassphrase("Notebook passphrase: ")
+	if err != nil {
+		return nil, err
+	}
+	scryptIdentity, err := age.NewScryptIdentity(passphrase)
+	if err != nil {
+		return nil, err
+	}
+	r, err := age.Decrypt(bytes.NewReader(b), scryptIdentity)
+	if err != nil {
+		return nil, fmt.Errorf("wrong passphrase or corrupt %s: %w", identityFile, err)
+	}
+	plain, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	return age.ParseX25519Identity(strings.TrimSpace(string(plain)))
+}
+
+// cmdUnlock decrypts the identity once and starts (or refreshes) the
+// background agent holding it for ttl.
+func cmdUnlock(args []string) error {
+	identity, err := decryptIdentityFile(activeNotebook.Dir)
+	if err != nil {
+		return err
+	}
+	ttl := agentTTL()
+	sock := agentSockPath(activeNotebook.Dir)
+	if err := startAgent(sock, identity.(*age.X25519Identity).String(), ttl); err != nil {
+		return fmt.Errorf("start key agent: %w", err)
+	}
+	fmt.Printf("Unlocked. Cached for %s (agent socket %s).\n", ttl, sock)
+	return nil
+}
+
+// cmdLockNotebook tells a running agent to forget its cached identity.
+func cmdLockNotebook(args []string) error {
+	sock := agentSockPath(activeNotebook.Dir)
+	conn, err := net.Dial("unix", sock)
+	if err != nil {
+		fmt.Println("Already locked (no agent running).")
+		return nil
+	}
+	defer conn.Close()
+	fmt.Fprintln(conn, "LOCK")
+	fmt.Println("Locked.")
+	return nil
+}
+
+// startAgent forks `notes agent-serve` detached from the current terminal,
+// passing it the identity over a pipe so it never appears in argv or the
+// environment, then waits for its socket to come up.
+func startAgent(sock, identityStr string, ttl time.Duration) error {
+	_ = os.Remove(sock)
+	cmd := exec.Command(os.Args[0], "agent-serve", sock, ttl.String())
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return err
+	}
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setsid: true}
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	go func() {
+		fmt.Fprintln(stdin, identityStr)
+		stdin.Close()
+	}()
+	for i := 0; i < 50; i++ {
+		if conn, err := net.Dial("unix", sock); err == nil {
+			conn.Close()
+			return nil
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	return errors.New("agent did not come up in time")
+}
+
+// runAgentServe is the body of the hidden `notes agent-serve <sock> <ttl>`
+// subcommand: read the identity from stdin, then serve GET/LOCK over the
+// unix socket until ttl elapses or a LOCK request arrives.
+func runAgentServe(args []string) error {
+	if len(args) < 2 {
+		return errors.New("usage: agent-serve <sock> <ttl>")
+	}
+	sock := args[0]
+	ttl, err := time.ParseDuration(args[1])
+	if err != nil {
+		return err
+	}
+	identityLine, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil {
+		return err
+	}
+	identity := strings.TrimSpace(identityLine)
+
+	l, err := net.Listen("unix", sock)
+	if err != nil {
+		return err
+	}
+	defer l.Close()
+	defer os.Remove(sock)
+	// Belt and suspenders on top of agentSockDir's 0700 directory: pin
+	// the socket file itself to owner-only too, in case it was ever
+	// created outside agentSockDir (e.g. via NOTES_AGENT_SOCK).
+	if err := os.Chmod(sock, 0o600); err != nil {
+		return fmt.Errorf("chmod agent socket: %w", err)
+	}
+
+	expire := time.Now().Add(ttl)
+	locked := make(chan struct{})
+	go func() {
+		time.Sleep(time.Until(expire))
+		close(locked)
+	}()
+
+	for {
+		l.(*net.UnixListener).SetDeadline(time.Now().Add(500 * time.Millisecond))
+		conn, err := l.Accept()
+		select {
+		case <-locked:
+			return nil
+		default:
+		}
+		if err != nil {
+			continue
+		}
+		line, _ := bufio.NewReader(conn).ReadString('\n')
+		switch strings.TrimSpace(line) {
+		case "GET":
+			if time.Now().After(expire) {
+				fmt.Fprintln(conn, "ERR expired")
+			} else {
+				fmt.Fprintln(conn, "OK "+identity)
+			}
+		case "LOCK":
+			fmt.Fprintln(conn, "OK")
+			conn.Close()
+			return nil
+		}
+		conn.Close()
+	}
+}
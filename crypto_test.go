@@ -0,0 +1,147 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"filippo.io/age"
+)
+
+func TestNotebookEncryptedFalseByDefault(t *testing.T) {
+	dir := t.TempDir()
+	if notebookEncrypted(dir) {
+		t.Fatal("a plain directory with no .age-recipients must not report encrypted")
+	}
+}
+
+func TestNotebookCipherPlainWhenNoRecipients(t *testing.T) {
+	dir := t.TempDir()
+	cipher, err := notebookCipher(dir)
+	if err != nil {
+		t.Fatalf("notebookCipher: %v", err)
+	}
+	if _, ok := cipher.(plainCipher); !ok {
+		t.Fatalf("notebookCipher(%s) = %T, want plainCipher", dir, cipher)
+	}
+}
+
+func TestNotebookCipherAgeRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	identity, err := age.GenerateX25519Identity()
+	if err != nil {
+		t.Fatalf("GenerateX25519Identity: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, recipientsFile), []byte(identity.Recipient().String()+"\n"), 0o644); err != nil {
+		t.Fatalf("write recipients file: %v", err)
+	}
+	if !notebookEncrypted(dir) {
+		t.Fatal("a directory with .age-recipients must report encrypted")
+	}
+	cipher, err := notebookCipher(dir)
+	if err != nil {
+		t.Fatalf("notebookCipher: %v", err)
+	}
+	ac, ok := cipher.(*ageCipher)
+	if !ok {
+		t.Fatalf("notebookCipher(%s) = %T, want *ageCipher", dir, cipher)
+	}
+	plain := []byte(`{"id":1,"title":"secret"}`)
+	ciphertext, err := ac.Encrypt(plain)
+	if err != nil {
+		t.Fatalf("Encrypt: %v", err)
+	}
+	if string(ciphertext) == string(plain) {
+		t.Fatal("Encrypt returned the plaintext unchanged")
+	}
+	r, err := age.Decrypt(bytes.NewReader(ciphertext), identity)
+	if err != nil {
+		t.Fatalf("age.Decrypt with the generated identity: %v", err)
+	}
+	got, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatalf("ReadAll: %v", err)
+	}
+	if string(got) != string(plain) {
+		t.Fatalf("decrypted %q, want %q", got, plain)
+	}
+}
+
+func TestAgentSockPathIsStableForSameDir(t *testing.T) {
+	dir := t.TempDir()
+	if agentSockPath(dir) != agentSockPath(dir) {
+		t.Fatal("agentSockPath must be stable for the same notebook dir")
+	}
+	if agentSockPath(dir) == agentSockPath(t.TempDir()) {
+		t.Fatal("agentSockPath must differ for different notebook dirs")
+	}
+}
+
+// TestAgentSockDirIsPrivate guards against the agent socket living
+// directly in the shared, world-writable os.TempDir(), where its access
+// control would depend entirely on the process umask.
+func TestAgentSockDirIsPrivate(t *testing.T) {
+	dir := agentSockDir()
+	info, err := os.Stat(dir)
+	if err != nil {
+		t.Fatalf("Stat(%s): %v", dir, err)
+	}
+	if perm := info.Mode().Perm(); perm != 0o700 {
+		t.Errorf("agentSockDir() = %s with perm %o, want 0700", dir, perm)
+	}
+}
+
+// TestRunAgentServeSocketIsOwnerOnly drives runAgentServe over a real
+// unix socket and checks the socket file itself ends up owner-only, on
+// top of agentSockDir's 0700 directory.
+func TestRunAgentServeSocketIsOwnerOnly(t *testing.T) {
+	sock := filepath.Join(t.TempDir(), "agent.sock")
+	origStdin := os.Stdin
+	pr, pw, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("Pipe: %v", err)
+	}
+	os.Stdin = pr
+	defer func() { os.Stdin = origStdin }()
+	fmt.Fprintln(pw, "AGE-SECRET-KEY-TESTIDENTITY")
+	pw.Close()
+
+	done := make(chan error, 1)
+	go func() { done <- runAgentServe([]string{sock, "5s"}) }()
+
+	var info os.FileInfo
+	for i := 0; i < 100; i++ {
+		info, err = os.Stat(sock)
+		if err == nil {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if err != nil {
+		t.Fatalf("agent socket never appeared: %v", err)
+	}
+	if perm := info.Mode().Perm(); perm != 0o600 {
+		t.Errorf("agent socket perm = %o, want 0600", perm)
+	}
+
+	conn, err := net.Dial("unix", sock)
+	if err != nil {
+		t.Fatalf("Dial: %v", err)
+	}
+	fmt.Fprintln(conn, "LOCK")
+	conn.Close()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("runAgentServe: %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("runAgentServe did not shut down after LOCK")
+	}
+}
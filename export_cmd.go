@@ -0,0 +1,115 @@
+// export_cmd.go
+// cmdExport is the `notes export` CLI front-end: it parses --format/
+// --template/--all/--out and drives an Exporter (single note) or
+// exportSite (whole notebook as a static site).
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+type exportOptions struct {
+	format   string
+	template string
+	all      bool
+	out      string
+	rest     []string
+}
+
+func parseExportArgs(args []string) (exportOptions, error) {
+	var o exportOptions
+	for i := 0; i < len(args); i++ {
+		a := args[i]
+		switch {
+		case a == "--all":
+			o.all = true
+		case a == "--format" || strings.HasPrefix(a, "--format="):
+			v, n, err := flagValue(args, i, a, "--format")
+			if err != nil {
+				return o, err
+			}
+			o.format, i = v, n
+		case a == "--template" || strings.HasPrefix(a, "--template="):
+			v, n, err := flagValue(args, i, a, "--template")
+			if err != nil {
+				return o, err
+			}
+			o.template, i = v, n
+		case a == "--out" || strings.HasPrefix(a, "--out="):
+			v, n, err := flagValue(args, i, a, "--out")
+			if err != nil {
+				return o, err
+			}
+			o.out, i = v, n
+		default:
+			o.rest = append(o.rest, a)
+		}
+	}
+	return o, nil
+}
+
+// flagValue resolves "--name value" or "--name=value" starting at args[i],
+// returning the value and the index to resume the loop from.
+func flagValue(args []string, i int, a, name string) (string, int, error) {
+	if v, ok := strings.CutPrefix(a, name+"="); ok {
+		return v, i, nil
+	}
+	if i+1 >= len(args) {
+		return "", i, fmt.Errorf("%s requires a value", name)
+	}
+	return args[i+1], i + 1, nil
+}
+
+func cmdExport(args []string) error {
+	o, err := parseExportArgs(args)
+	if err != nil {
+		return err
+	}
+	store, err := openStorage()
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+	if o.template == "" {
+		o.template = activeNotebook.Template
+	}
+	exporter := newExporter(o.format, o.template)
+
+	if o.all {
+		if o.out == "" {
+			return errors.New("usage: export --all --out <dir> [--format F] [--template T]")
+		}
+		notes, err := store.All()
+		if err != nil {
+			return err
+		}
+		n, err := exportSite(exporter, notes, o.out)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Exported %d note(s) to %s\n", n, o.out)
+		return nil
+	}
+
+	if len(o.rest) < 2 {
+		return errors.New("usage: export <id> <file> [--format F] [--template T]")
+	}
+	id, err := strconv.Atoi(o.rest[0])
+	if err != nil {
+		return err
+	}
+	out := o.rest[1]
+	n, err := store.Load(id)
+	if err != nil {
+		return err
+	}
+	if err := exporter.ExportTo(*n, out); err != nil {
+		return err
+	}
+	fmt.Printf("Exported note #%d to %s\n", id, out)
+	return nil
+}
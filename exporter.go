@@ -0,0 +1,231 @@
+// exporter.go
+// Exporter renders a Note through goldmark (CommonMark + GFM tables,
+// strikethrough, task lists, fenced code) instead of the old line-by-line
+// "# " scanner. HTML output gets chroma syntax highlighting and a
+// table-of-contents built from the heading IDs goldmark assigns; `pdf`,
+// `pandoc` and `epub` formats are produced by piping the rendered Markdown
+// through the `pandoc` binary, which already knows those formats far
+// better than anything we'd hand-roll here.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/yuin/goldmark"
+	highlighting "github.com/yuin/goldmark-highlighting/v2"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/extension"
+	"github.com/yuin/goldmark/parser"
+	goldmarkhtml "github.com/yuin/goldmark/renderer/html"
+	"github.com/yuin/goldmark/text"
+)
+
+// tocEntry is one entry in a rendered note's table of contents.
+type tocEntry struct {
+	Level int
+	Text  string
+	ID    string
+}
+
+// exportTemplateData is what `--template` files are rendered against.
+type exportTemplateData struct {
+	Title    string
+	Tags     []string
+	Created  string
+	BodyHTML template.HTML
+	TOC      []tocEntry
+}
+
+const defaultHTMLTemplate = `<!doctype html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<style>
+body { font: 16px/1.5 -apple-system, sans-serif; max-width: 46rem; margin: 2rem auto; padding: 0 1rem; }
+nav.toc { border-left: 2px solid #ddd; padding-left: 1rem; margin-bottom: 2rem; font-size: 0.9em; }
+pre { background: #f6f8fa; padding: 0.75rem; overflow-x: auto; }
+.tags { color: #666; font-size: 0.9em; }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+<p class="tags">{{range .Tags}}#{{.}} {{end}}&middot; {{.Created}}</p>
+{{if .TOC}}<nav class="toc"><strong>Contents</strong><ul>
+{{range .TOC}}<li style="margin-left: {{.Level}}em"><a href="#{{.ID}}">{{.Text}}</a></li>
+{{end}}</ul></nav>{{end}}
+{{.BodyHTML}}
+</body>
+</html>
+`
+
+// Exporter renders notes for `notes export`. format is one of
+// html|pdf|pandoc|epub|json; template, if set, overrides defaultHTMLTemplate
+// for the html format.
+type Exporter struct {
+	Format   string
+	Template string
+	md       goldmark.Markdown
+}
+
+func newExporter(format, templatePath string) *Exporter {
+	md := goldmark.New(
+		goldmark.WithExtensions(extension.GFM, WikiLinkExtension, highlighting.NewHighlighting(
+			highlighting.WithStyle("github"),
+		)),
+		goldmark.WithParserOptions(parser.WithAutoHeadingID()),
+		goldmark.WithRendererOptions(goldmarkhtml.WithUnsafe()),
+	)
+	return &Exporter{Format: format, Template: templatePath, md: md}
+}
+
+// renderBody converts Markdown body to HTML plus a heading-derived TOC.
+// Both are read off the same parsed AST, so the TOC's anchor IDs are
+// exactly the ids WithAutoHeadingID assigned the rendered headings
+// (including the "-1"/"-2" suffixes it appends to duplicate headings),
+// instead of a second, independently-computed slug that can disagree.
+func (e *Exporter) renderBody(body string) (template.HTML, []tocEntry, error) {
+	source := []byte(body)
+	doc := e.md.Parser().Parse(text.NewReader(source))
+	var buf bytes.Buffer
+	if err := e.md.Renderer().Render(&buf, source, doc); err != nil {
+		return "", nil, fmt.Errorf("render markdown: %w", err)
+	}
+	return template.HTML(buf.String()), headingTOC(doc, source), nil
+}
+
+// headingTOC walks the parsed AST for heading nodes and reads back the id
+// goldmark's WithAutoHeadingID assigned each one.
+func headingTOC(doc ast.Node, source []byte) []tocEntry {
+	var toc []tocEntry
+	ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		h, ok := n.(*ast.Heading)
+		if !ok {
+			return ast.WalkContinue, nil
+		}
+		var id string
+		if v, ok := h.AttributeString("id"); ok {
+			if b, ok := v.([]byte); ok {
+				id = string(b)
+			}
+		}
+		toc = append(toc, tocEntry{Level: h.Level, Text: headingText(h, source), ID: id})
+		return ast.WalkContinue, nil
+	})
+	return toc
+}
+
+// headingText concatenates a heading's inline text nodes, ignoring
+// formatting markup, for display in the TOC.
+func headingText(h *ast.Heading, source []byte) string {
+	var b strings.Builder
+	ast.Walk(h, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if entering {
+			if t, ok := n.(*ast.Text); ok {
+				b.Write(t.Segment.Value(source))
+			}
+		}
+		return ast.WalkContinue, nil
+	})
+	return b.String()
+}
+
+func slugify(s string) string {
+	s = strings.ToLower(s)
+	var b strings.Builder
+	lastDash := false
+	for _, r := range s {
+		switch {
+		case r >= 'a' && r <= 'z' || r >= '0' && r <= '9':
+			b.WriteRune(r)
+			lastDash = false
+		default:
+			if !lastDash {
+				b.WriteByte('-')
+				lastDash = true
+			}
+		}
+	}
+	return strings.Trim(b.String(), "-")
+}
+
+func (e *Exporter) htmlTemplate() (*template.Template, error) {
+	if e.Template == "" {
+		return template.New("note").Parse(defaultHTMLTemplate)
+	}
+	b, err := os.ReadFile(e.Template)
+	if err != nil {
+		return nil, fmt.Errorf("read template %s: %w", e.Template, err)
+	}
+	return template.New("note").Parse(string(b))
+}
+
+// ExportTo renders n and writes it to outPath according to e.Format.
+func (e *Exporter) ExportTo(n Note, outPath string) error {
+	switch e.Format {
+	case "", "html":
+		bodyHTML, toc, err := e.renderBody(n.Body)
+		if err != nil {
+			return err
+		}
+		tpl, err := e.htmlTemplate()
+		if err != nil {
+			return err
+		}
+		var buf bytes.Buffer
+		data := exportTemplateData{
+			Title:    n.Title,
+			Tags:     n.Tags,
+			Created:  n.Created.Format("2006-01-02"),
+			BodyHTML: bodyHTML,
+			TOC:      toc,
+		}
+		if err := tpl.Execute(&buf, data); err != nil {
+			return fmt.Errorf("render template: %w", err)
+		}
+		return os.WriteFile(outPath, buf.Bytes(), 0o644)
+	case "json":
+		b, err := json.MarshalIndent(n, "", "  ")
+		if err != nil {
+			return err
+		}
+		return os.WriteFile(outPath, b, 0o644)
+	case "pdf", "epub", "pandoc":
+		return e.exportViaPandoc(n, outPath)
+	default:
+		return fmt.Errorf("unknown export format %q", e.Format)
+	}
+}
+
+// exportViaPandoc shells out to `pandoc` for formats that need a real
+// document engine (pdf via LaTeX, epub packaging, or a pandoc-native
+// format the caller names via --format pandoc:<target>).
+func (e *Exporter) exportViaPandoc(n Note, outPath string) error {
+	target := e.Format
+	if strings.HasPrefix(e.Format, "pandoc:") {
+		target = strings.TrimPrefix(e.Format, "pandoc:")
+	} else if e.Format == "pandoc" {
+		target = "markdown"
+	}
+	args := []string{"--metadata", "title=" + n.Title, "-o", outPath}
+	if target != "" && target != "pandoc" {
+		args = append(args, "-t", target)
+	}
+	cmd := exec.Command("pandoc", args...)
+	cmd.Stdin = strings.NewReader(n.Body)
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("pandoc export to %s: %w", target, err)
+	}
+	return nil
+}
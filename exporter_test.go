@@ -0,0 +1,43 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSlugify(t *testing.T) {
+	cases := map[string]string{
+		"Work/Urgent": "work-urgent",
+		"  spaced  ":  "spaced",
+		"a__b":        "a-b",
+	}
+	for in, want := range cases {
+		if got := slugify(in); got != want {
+			t.Errorf("slugify(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+// TestHeadingTOCMatchesRenderedIDs guards against the TOC anchors and the
+// rendered heading ids drifting apart again: both must come from the
+// same AST, including the "-1" suffix goldmark's WithAutoHeadingID gives
+// a duplicate heading.
+func TestHeadingTOCMatchesRenderedIDs(t *testing.T) {
+	e := newExporter("html", "")
+	body := "# Intro\n\nhello\n\n# Intro\n\nagain\n"
+	html, toc, err := e.renderBody(body)
+	if err != nil {
+		t.Fatalf("renderBody: %v", err)
+	}
+	if len(toc) != 2 {
+		t.Fatalf("got %d TOC entries, want 2: %+v", len(toc), toc)
+	}
+	if toc[0].ID == toc[1].ID {
+		t.Fatalf("duplicate headings got the same TOC id %q", toc[0].ID)
+	}
+	for _, entry := range toc {
+		if entry.ID == "" || !strings.Contains(string(html), `id="`+entry.ID+`"`) {
+			t.Errorf("rendered HTML has no heading with id=%q: %s", entry.ID, html)
+		}
+	}
+}
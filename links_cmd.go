@@ -0,0 +1,178 @@
+// links_cmd.go
+// CLI front-ends for wiki-link navigation: `notes links`, `notes
+// backlinks`, `notes graph`, and the broken-link check folded into
+// `notes lint`.
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+func cmdLinks(args []string) error {
+	if len(args) == 0 {
+		return errors.New("usage: links <id>")
+	}
+	id, err := strconv.Atoi(args[0])
+	if err != nil {
+		return err
+	}
+	store, err := openStorage()
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+	out, err := outgoingLinks(store, id)
+	if err != nil {
+		return err
+	}
+	if len(out) == 0 {
+		fmt.Println("No outgoing links.")
+		return nil
+	}
+	for _, n := range out {
+		fmt.Printf("%3d  %s\n", n.ID, n.Title)
+	}
+	return nil
+}
+
+func cmdBacklinks(args []string) error {
+	if len(args) == 0 {
+		return errors.New("usage: backlinks <id>")
+	}
+	id, err := strconv.Atoi(args[0])
+	if err != nil {
+		return err
+	}
+	store, err := openStorage()
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+	in, err := incomingLinks(store, id)
+	if err != nil {
+		return err
+	}
+	if len(in) == 0 {
+		fmt.Println("No backlinks.")
+		return nil
+	}
+	for _, n := range in {
+		fmt.Printf("%3d  %s\n", n.ID, n.Title)
+	}
+	return nil
+}
+
+// cmdGraph emits the full note graph via --format dot|json|mermaid
+// (--out writes to a file instead of stdout).
+func cmdGraph(args []string) error {
+	format := "dot"
+	out := ""
+	for i := 0; i < len(args); i++ {
+		switch {
+		case args[i] == "--format" && i+1 < len(args):
+			format, i = args[i+1], i+1
+		case strings.HasPrefix(args[i], "--format="):
+			format = strings.TrimPrefix(args[i], "--format=")
+		case args[i] == "--out" && i+1 < len(args):
+			out, i = args[i+1], i+1
+		case strings.HasPrefix(args[i], "--out="):
+			out = strings.TrimPrefix(args[i], "--out=")
+		}
+	}
+	store, err := openStorage()
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+	notes, err := store.All()
+	if err != nil {
+		return err
+	}
+	// One linkIndex for the whole command: resolving each note's refs
+	// against it directly (outgoingLinksFor) instead of calling
+	// outgoingLinks per note avoids rebuilding the index, and reloading
+	// every note, once per note.
+	idx := newLinkIndex(notes)
+
+	var b strings.Builder
+	switch format {
+	case "dot":
+		b.WriteString("digraph notes {\n")
+		for _, n := range notes {
+			fmt.Fprintf(&b, "  %d [label=%q];\n", n.ID, n.Title)
+		}
+		for _, n := range notes {
+			for _, l := range outgoingLinksFor(n, idx) {
+				fmt.Fprintf(&b, "  %d -> %d;\n", n.ID, l.ID)
+			}
+		}
+		b.WriteString("}\n")
+	case "mermaid":
+		b.WriteString("graph LR\n")
+		for _, n := range notes {
+			for _, l := range outgoingLinksFor(n, idx) {
+				fmt.Fprintf(&b, "  %d[%q] --> %d[%q]\n", n.ID, n.Title, l.ID, l.Title)
+			}
+		}
+	case "json":
+		type edge struct {
+			From int `json:"from"`
+			To   int `json:"to"`
+		}
+		type graph struct {
+			Notes []Note `json:"notes"`
+			Edges []edge `json:"edges"`
+		}
+		g := graph{Notes: notes}
+		for _, n := range notes {
+			for _, l := range outgoingLinksFor(n, idx) {
+				g.Edges = append(g.Edges, edge{From: n.ID, To: l.ID})
+			}
+		}
+		buf, err := json.MarshalIndent(g, "", "  ")
+		if err != nil {
+			return err
+		}
+		b.Write(buf)
+	default:
+		return fmt.Errorf("unknown graph format %q (want dot, json or mermaid)", format)
+	}
+
+	if out == "" {
+		fmt.Print(b.String())
+		return nil
+	}
+	if err := os.WriteFile(out, []byte(b.String()), 0o644); err != nil {
+		return err
+	}
+	fmt.Printf("Wrote graph to %s\n", out)
+	return nil
+}
+
+func cmdLint(args []string) error {
+	store, err := openStorage()
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+	broken, err := brokenLinks(store)
+	if err != nil {
+		return err
+	}
+	if len(broken) == 0 {
+		fmt.Println("No issues found.")
+		return nil
+	}
+	sort.Slice(broken, func(i, j int) bool { return broken[i].FromID < broken[j].FromID })
+	for _, bl := range broken {
+		fmt.Printf("note #%d %q: broken link [[%s]]\n", bl.FromID, bl.FromTitle, bl.Ref)
+	}
+	return fmt.Errorf("%d broken link(s) found", len(broken))
+}
@@ -0,0 +1,131 @@
+package main
+
+import "testing"
+
+func TestExtractWikiLinkRefs(t *testing.T) {
+	refs := extractWikiLinkRefs("See [[Other Note]] and [[42]], twice [[42]].")
+	want := []string{"Other Note", "42", "42"}
+	if len(refs) != len(want) {
+		t.Fatalf("got %v, want %v", refs, want)
+	}
+	for i, r := range refs {
+		if r != want[i] {
+			t.Errorf("refs[%d] = %q, want %q", i, r, want[i])
+		}
+	}
+}
+
+func newTestStore(t *testing.T, notes ...Note) Storage {
+	t.Helper()
+	store, err := newJSONStore(t.TempDir(), GroupConfig{})
+	if err != nil {
+		t.Fatalf("newJSONStore: %v", err)
+	}
+	for _, n := range notes {
+		if err := store.Save(n); err != nil {
+			t.Fatalf("Save: %v", err)
+		}
+	}
+	return store
+}
+
+func TestOutgoingLinksResolvesByIDAndTitle(t *testing.T) {
+	store := newTestStore(t,
+		Note{ID: 1, Title: "Root", Body: "[[2]] and [[Leaf]]"},
+		Note{ID: 2, Title: "Mid"},
+		Note{ID: 3, Title: "Leaf"},
+	)
+	out, err := outgoingLinks(store, 1)
+	if err != nil {
+		t.Fatalf("outgoingLinks: %v", err)
+	}
+	if len(out) != 2 || out[0].ID != 2 || out[1].ID != 3 {
+		t.Fatalf("outgoingLinks(1) = %+v, want notes 2 and 3", out)
+	}
+}
+
+func TestOutgoingLinksSkipsDanglingRefs(t *testing.T) {
+	store := newTestStore(t, Note{ID: 1, Title: "Root", Body: "[[999]] [[Nowhere]]"})
+	out, err := outgoingLinks(store, 1)
+	if err != nil {
+		t.Fatalf("outgoingLinks: %v", err)
+	}
+	if len(out) != 0 {
+		t.Fatalf("outgoingLinks(1) = %+v, want none", out)
+	}
+}
+
+func TestResolveWikiLinkDanglingIDIsNotAnError(t *testing.T) {
+	store := newTestStore(t, Note{ID: 1, Title: "Root"})
+	n, err := resolveWikiLink(store, "999")
+	if err != nil {
+		t.Fatalf("resolveWikiLink returned an error for a dangling id: %v", err)
+	}
+	if n != nil {
+		t.Fatalf("resolveWikiLink(999) = %+v, want nil", n)
+	}
+}
+
+func TestBrokenLinksReportsDanglingRefsOnly(t *testing.T) {
+	store := newTestStore(t,
+		Note{ID: 1, Title: "Root", Body: "[[2]] [[Ghost]]"},
+		Note{ID: 2, Title: "Mid"},
+	)
+	broken, err := brokenLinks(store)
+	if err != nil {
+		t.Fatalf("brokenLinks: %v", err)
+	}
+	if len(broken) != 1 || broken[0].Ref != "Ghost" {
+		t.Fatalf("brokenLinks = %+v, want exactly one broken ref %q", broken, "Ghost")
+	}
+}
+
+func TestIncomingLinks(t *testing.T) {
+	store := newTestStore(t,
+		Note{ID: 1, Title: "A", Body: "[[3]]"},
+		Note{ID: 2, Title: "B", Body: "[[Leaf]]"},
+		Note{ID: 3, Title: "Leaf"},
+	)
+	in, err := incomingLinks(store, 3)
+	if err != nil {
+		t.Fatalf("incomingLinks: %v", err)
+	}
+	if len(in) != 2 {
+		t.Fatalf("incomingLinks(3) = %+v, want notes 1 and 2", in)
+	}
+}
+
+// TestOutgoingLinksForMatchesOutgoingLinks guards cmdGraph's shared-
+// linkIndex path against drifting from the per-note outgoingLinks it
+// replaced there.
+func TestOutgoingLinksForMatchesOutgoingLinks(t *testing.T) {
+	store := newTestStore(t,
+		Note{ID: 1, Title: "Root", Body: "[[2]] [[Leaf]]"},
+		Note{ID: 2, Title: "Mid"},
+		Note{ID: 3, Title: "Leaf"},
+	)
+	want, err := outgoingLinks(store, 1)
+	if err != nil {
+		t.Fatalf("outgoingLinks: %v", err)
+	}
+	notes, err := store.All()
+	if err != nil {
+		t.Fatalf("All: %v", err)
+	}
+	idx := newLinkIndex(notes)
+	var root Note
+	for _, n := range notes {
+		if n.ID == 1 {
+			root = n
+		}
+	}
+	got := outgoingLinksFor(root, idx)
+	if len(got) != len(want) {
+		t.Fatalf("outgoingLinksFor = %+v, want %+v", got, want)
+	}
+	for i := range got {
+		if got[i].ID != want[i].ID {
+			t.Fatalf("outgoingLinksFor[%d].ID = %d, want %d", i, got[i].ID, want[i].ID)
+		}
+	}
+}
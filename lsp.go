@@ -0,0 +1,429 @@
+// lsp.go
+// `notes lsp` speaks a minimal Language Server Protocol server over stdio
+// so editors can list/search/tag/create notes and get completion for
+// [[wiki-link]] and #tag tokens, without shelling out to the CLI. It is a
+// hand-rolled JSON-RPC 2.0 loop rather than a `go.lsp.dev/protocol` client,
+// since we only need a handful of methods and the request/notification
+// shapes are trivial to frame ourselves. It reuses the same Storage used
+// by the CLI commands so both stay in sync.
+//
+// A client may open more than one notebook root in a single session (a
+// personal notebook and a work notebook side by side, say): `initialize`'s
+// workspaceFolders are each opened into a Workspace (see config.go), and
+// commands that operate on an open document route to whichever notebook's
+// directory contains it, falling back to the notebook `notes lsp` itself
+// was started against when only one is open.
+
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type rpcMessage struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method,omitempty"`
+	Params  json.RawMessage `json:"params,omitempty"`
+	Result  interface{}     `json:"result,omitempty"`
+	Error   *rpcError       `json:"error,omitempty"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+type lspPosition struct {
+	Line      int `json:"line"`
+	Character int `json:"character"`
+}
+
+type lspRange struct {
+	Start lspPosition `json:"start"`
+	End   lspPosition `json:"end"`
+}
+
+type lspLocation struct {
+	URI   string   `json:"uri"`
+	Range lspRange `json:"range"`
+}
+
+// lspServer holds the in-memory documents editors have opened, keyed by
+// URI, so completion/definition can inspect the current buffer instead of
+// whatever was last saved to disk.
+type lspServer struct {
+	workspace *Workspace
+	defaultNB *Notebook
+	docs      map[string][]string // uri -> lines
+	out       *bufio.Writer
+}
+
+func runLSP(r io.Reader, w io.Writer, nb *Notebook) error {
+	ws := NewWorkspace()
+	if _, err := ws.Open(nb); err != nil {
+		return err
+	}
+	defer ws.Close()
+	s := &lspServer{workspace: ws, defaultNB: nb, docs: map[string][]string{}, out: bufio.NewWriter(w)}
+	br := bufio.NewReader(r)
+	for {
+		msg, err := readRPCMessage(br)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := s.handle(msg); err != nil {
+			return err
+		}
+	}
+}
+
+func readRPCMessage(br *bufio.Reader) (*rpcMessage, error) {
+	contentLength := -1
+	for {
+		line, err := br.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		line = strings.TrimRight(line, "\r\n")
+		if line == "" {
+			break
+		}
+		if strings.HasPrefix(strings.ToLower(line), "content-length:") {
+			n, err := strconv.Atoi(strings.TrimSpace(line[len("content-length:"):]))
+			if err != nil {
+				return nil, fmt.Errorf("bad Content-Length header %q: %w", line, err)
+			}
+			contentLength = n
+		}
+	}
+	if contentLength < 0 {
+		return nil, fmt.Errorf("missing Content-Length header")
+	}
+	buf := make([]byte, contentLength)
+	if _, err := io.ReadFull(br, buf); err != nil {
+		return nil, err
+	}
+	var msg rpcMessage
+	if err := json.Unmarshal(buf, &msg); err != nil {
+		return nil, err
+	}
+	return &msg, nil
+}
+
+func (s *lspServer) reply(id json.RawMessage, result interface{}, rpcErr *rpcError) error {
+	msg := rpcMessage{JSONRPC: "2.0", ID: id, Result: result, Error: rpcErr}
+	b, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintf(s.out, "Content-Length: %d\r\n\r\n%s", len(b), b); err != nil {
+		return err
+	}
+	return s.out.Flush()
+}
+
+func (s *lspServer) handle(msg *rpcMessage) error {
+	switch msg.Method {
+	case "initialize":
+		var p struct {
+			WorkspaceFolders []struct {
+				URI string `json:"uri"`
+			} `json:"workspaceFolders"`
+		}
+		_ = json.Unmarshal(msg.Params, &p)
+		for _, f := range p.WorkspaceFolders {
+			root := uriToPath(f.URI)
+			if root == "" {
+				continue
+			}
+			nb, err := notebookConfigAt(root)
+			if err != nil {
+				continue
+			}
+			if nb == nil {
+				nb = &Notebook{Dir: root}
+			}
+			_, _ = s.workspace.Open(nb)
+		}
+		return s.reply(msg.ID, map[string]interface{}{
+			"capabilities": map[string]interface{}{
+				"textDocumentSync":   1, // full document sync
+				"completionProvider": map[string]interface{}{"triggerCharacters": []string{"[", "#"}},
+				"definitionProvider": true,
+				"executeCommandProvider": map[string]interface{}{
+					"commands": []string{"notes.list", "notes.tag.list", "notes.new"},
+				},
+			},
+		}, nil)
+	case "initialized", "exit":
+		return nil
+	case "shutdown":
+		return s.reply(msg.ID, nil, nil)
+	case "textDocument/didOpen":
+		var p struct {
+			TextDocument struct {
+				URI  string `json:"uri"`
+				Text string `json:"text"`
+			} `json:"textDocument"`
+		}
+		if err := json.Unmarshal(msg.Params, &p); err != nil {
+			return err
+		}
+		s.docs[p.TextDocument.URI] = strings.Split(p.TextDocument.Text, "\n")
+		return nil
+	case "textDocument/didChange":
+		var p struct {
+			TextDocument struct {
+				URI string `json:"uri"`
+			} `json:"textDocument"`
+			ContentChanges []struct {
+				Text string `json:"text"`
+			} `json:"contentChanges"`
+		}
+		if err := json.Unmarshal(msg.Params, &p); err != nil {
+			return err
+		}
+		if len(p.ContentChanges) > 0 {
+			s.docs[p.TextDocument.URI] = strings.Split(p.ContentChanges[len(p.ContentChanges)-1].Text, "\n")
+		}
+		return nil
+	case "textDocument/completion":
+		return s.handleCompletion(msg)
+	case "textDocument/definition":
+		return s.handleDefinition(msg)
+	case "workspace/executeCommand":
+		return s.handleExecuteCommand(msg)
+	default:
+		if msg.ID != nil {
+			return s.reply(msg.ID, nil, &rpcError{Code: -32601, Message: "method not found: " + msg.Method})
+		}
+		return nil
+	}
+}
+
+func (s *lspServer) line(uri string, n int) string {
+	lines := s.docs[uri]
+	if n < 0 || n >= len(lines) {
+		return ""
+	}
+	return lines[n]
+}
+
+// uriToPath strips the file:// scheme LSP clients send workspace folder
+// and document URIs with; it doesn't try to handle other schemes.
+func uriToPath(uri string) string {
+	return strings.TrimPrefix(uri, "file://")
+}
+
+// storeFor picks the notebook a document belongs to, falling back to the
+// notebook `notes lsp` was started against.
+func (s *lspServer) storeFor(uri string) Storage {
+	if store := s.workspace.For(uriToPath(uri)); store != nil {
+		return store
+	}
+	return s.workspace.Get(s.defaultNB)
+}
+
+func (s *lspServer) handleCompletion(msg *rpcMessage) error {
+	var p struct {
+		TextDocument struct {
+			URI string `json:"uri"`
+		} `json:"textDocument"`
+		Position lspPosition `json:"position"`
+	}
+	if err := json.Unmarshal(msg.Params, &p); err != nil {
+		return err
+	}
+	store := s.storeFor(p.TextDocument.URI)
+	line := s.line(p.TextDocument.URI, p.Position.Line)
+	before := line
+	if p.Position.Character <= len(line) {
+		before = line[:p.Position.Character]
+	}
+	var items []map[string]interface{}
+	switch {
+	case strings.HasSuffix(before, "[["), wikiLinkOpenRe.MatchString(before):
+		notes, err := store.All()
+		if err != nil {
+			return err
+		}
+		for _, n := range notes {
+			items = append(items, map[string]interface{}{
+				"label":      n.Title,
+				"insertText": fmt.Sprintf("%s]]", n.Title),
+				"kind":       18, // CompletionItemKind.Reference
+			})
+		}
+	case strings.HasSuffix(before, "#"), tagOpenRe.MatchString(before):
+		tags, err := store.TagsList()
+		if err != nil {
+			return err
+		}
+		for _, t := range tags {
+			items = append(items, map[string]interface{}{
+				"label":  t.Tag,
+				"detail": fmt.Sprintf("%d note(s)", t.Count),
+				"kind":   12, // CompletionItemKind.Value
+			})
+		}
+	}
+	return s.reply(msg.ID, map[string]interface{}{"isIncomplete": false, "items": items}, nil)
+}
+
+var (
+	wikiLinkOpenRe = regexp.MustCompile(`\[\[[^\]]*$`)
+	tagOpenRe      = regexp.MustCompile(`#[A-Za-z0-9_-]*$`)
+	wikiLinkRe     = regexp.MustCompile(`\[\[([^\]]+)\]\]`)
+)
+
+func (s *lspServer) handleDefinition(msg *rpcMessage) error {
+	var p struct {
+		TextDocument struct {
+			URI string `json:"uri"`
+		} `json:"textDocument"`
+		Position lspPosition `json:"position"`
+	}
+	if err := json.Unmarshal(msg.Params, &p); err != nil {
+		return err
+	}
+	store := s.storeFor(p.TextDocument.URI)
+	line := s.line(p.TextDocument.URI, p.Position.Line)
+	for _, loc := range wikiLinkRe.FindAllStringSubmatchIndex(line, -1) {
+		start, end := loc[0], loc[1]
+		if p.Position.Character < start || p.Position.Character > end {
+			continue
+		}
+		ref := line[loc[2]:loc[3]]
+		target, err := resolveWikiLink(store, ref)
+		if err != nil || target == nil {
+			return s.reply(msg.ID, nil, nil)
+		}
+		return s.reply(msg.ID, lspLocation{
+			URI:   noteURI(target.ID),
+			Range: lspRange{},
+		}, nil)
+	}
+	return s.reply(msg.ID, nil, nil)
+}
+
+// noteURI returns the opaque notes:/// URI for id. jsonStore's on-disk
+// files are JSON (and age-encrypted for an encrypted notebook), not plain
+// markdown, so we can't hand editors a file:// URI and expect them to
+// render it; clients resolve notes:/// URIs back through the workspace
+// commands instead.
+func noteURI(id int) string {
+	return fmt.Sprintf("notes:///%d", id)
+}
+
+// notePathFor returns the on-disk path of note id within store, for
+// callers like notes.new that want to report where the note file lives.
+// Every Storage a Workspace opens is a *jsonStore (see Workspace.Open),
+// so this always resolves.
+func notePathFor(store Storage, id int) string {
+	if js, ok := store.(*jsonStore); ok {
+		return js.notePath(id)
+	}
+	return ""
+}
+
+// resolveWikiLink resolves a `[[Title]]` or `[[42]]` reference to a Note,
+// returning (nil, nil) for a dangling reference rather than erroring, so
+// callers like `notes lint` can report it instead of aborting.
+func resolveWikiLink(store Storage, ref string) (*Note, error) {
+	ref = strings.TrimSpace(ref)
+	if id, err := strconv.Atoi(ref); err == nil {
+		n, err := store.Load(id)
+		if errors.Is(err, ErrNoteNotFound) {
+			return nil, nil
+		}
+		return n, err
+	}
+	notes, err := store.All()
+	if err != nil {
+		return nil, err
+	}
+	for _, n := range notes {
+		if n.Title == ref {
+			return &n, nil
+		}
+	}
+	return nil, nil
+}
+
+func (s *lspServer) handleExecuteCommand(msg *rpcMessage) error {
+	var p struct {
+		Command   string            `json:"command"`
+		Arguments []json.RawMessage `json:"arguments"`
+	}
+	if err := json.Unmarshal(msg.Params, &p); err != nil {
+		return err
+	}
+	store := s.workspace.Get(s.defaultNB)
+	switch p.Command {
+	case "notes.list":
+		var arg struct {
+			Query string `json:"query"`
+			Tag   string `json:"tag"`
+		}
+		if len(p.Arguments) > 0 {
+			_ = json.Unmarshal(p.Arguments[0], &arg)
+		}
+		hits, err := store.Search(arg.Query, SearchFilters{Tag: arg.Tag})
+		if err != nil {
+			return s.reply(msg.ID, nil, &rpcError{Code: -32000, Message: err.Error()})
+		}
+		return s.reply(msg.ID, hits, nil)
+	case "notes.tag.list":
+		tags, err := store.TagsList()
+		if err != nil {
+			return s.reply(msg.ID, nil, &rpcError{Code: -32000, Message: err.Error()})
+		}
+		return s.reply(msg.ID, tags, nil)
+	case "notes.new":
+		var arg struct {
+			Title    string   `json:"title"`
+			Content  string   `json:"content"`
+			Tags     []string `json:"tags"`
+			Template string   `json:"template"`
+		}
+		if len(p.Arguments) > 0 {
+			_ = json.Unmarshal(p.Arguments[0], &arg)
+		}
+		notes, err := store.All()
+		if err != nil {
+			return s.reply(msg.ID, nil, &rpcError{Code: -32000, Message: err.Error()})
+		}
+		body := arg.Content
+		if body == "" && arg.Template != "" {
+			b, err := os.ReadFile(arg.Template)
+			if err != nil {
+				return s.reply(msg.ID, nil, &rpcError{Code: -32000, Message: fmt.Sprintf("read template %s: %s", arg.Template, err)})
+			}
+			body = string(b)
+		}
+		n := Note{ID: nextID(notes), Title: arg.Title, Body: body, Tags: arg.Tags, Created: time.Now().UTC()}
+		if err := store.Save(n); err != nil {
+			return s.reply(msg.ID, nil, &rpcError{Code: -32000, Message: err.Error()})
+		}
+		return s.reply(msg.ID, map[string]interface{}{
+			"id":   n.ID,
+			"path": notePathFor(store, n.ID),
+			"uri":  noteURI(n.ID),
+		}, nil)
+	default:
+		return s.reply(msg.ID, nil, &rpcError{Code: -32601, Message: "unknown command: " + p.Command})
+	}
+}
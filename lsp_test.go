@@ -0,0 +1,124 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+)
+
+func frameRPC(t *testing.T, method string, id int, params interface{}) []byte {
+	t.Helper()
+	req := map[string]interface{}{"jsonrpc": "2.0", "method": method, "params": params}
+	if id != 0 {
+		req["id"] = id
+	}
+	b, err := json.Marshal(req)
+	if err != nil {
+		t.Fatalf("marshal request: %v", err)
+	}
+	return []byte(fmt.Sprintf("Content-Length: %d\r\n\r\n%s", len(b), b))
+}
+
+// decodeFramedReplies splits runLSP's Content-Length framed output back
+// into individual decoded messages, reusing the server's own framing
+// reader so the test stays in sync with the wire format it produces.
+func decodeFramedReplies(t *testing.T, raw []byte) []rpcMessage {
+	t.Helper()
+	br := bufio.NewReader(bytes.NewReader(raw))
+	var out []rpcMessage
+	for {
+		msg, err := readRPCMessage(br)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("readRPCMessage: %v", err)
+		}
+		out = append(out, *msg)
+	}
+	return out
+}
+
+func TestRunLSPExecuteCommandNotesNew(t *testing.T) {
+	dir := t.TempDir()
+	nb := &Notebook{Dir: dir}
+
+	var reqs bytes.Buffer
+	reqs.Write(frameRPC(t, "initialize", 1, map[string]interface{}{}))
+	reqs.Write(frameRPC(t, "workspace/executeCommand", 2, map[string]interface{}{
+		"command":   "notes.new",
+		"arguments": []interface{}{map[string]interface{}{"title": "Hello", "content": "body text"}},
+	}))
+
+	var out bytes.Buffer
+	if err := runLSP(&reqs, &out, nb); err != nil {
+		t.Fatalf("runLSP: %v", err)
+	}
+
+	replies := decodeFramedReplies(t, out.Bytes())
+	if len(replies) != 2 {
+		t.Fatalf("got %d replies, want 2: %+v", len(replies), replies)
+	}
+	newReply := replies[1]
+	if newReply.Error != nil {
+		t.Fatalf("notes.new returned an error: %+v", newReply.Error)
+	}
+	result, ok := newReply.Result.(map[string]interface{})
+	if !ok {
+		t.Fatalf("notes.new result = %#v, want an object", newReply.Result)
+	}
+	if s, _ := result["path"].(string); s == "" {
+		t.Errorf("notes.new result has no path: %+v", result)
+	}
+	uri, _ := result["uri"].(string)
+	if !strings.HasPrefix(uri, "notes:///") {
+		t.Errorf("notes.new uri = %q, want a notes:/// URI (the on-disk file isn't plain markdown)", uri)
+	}
+
+	store, err := newJSONStore(dir, GroupConfig{})
+	if err != nil {
+		t.Fatalf("newJSONStore: %v", err)
+	}
+	notes, err := store.All()
+	if err != nil {
+		t.Fatalf("All: %v", err)
+	}
+	if len(notes) != 1 || notes[0].Title != "Hello" || notes[0].Body != "body text" {
+		t.Fatalf("notes.new did not persist the note, got %+v", notes)
+	}
+}
+
+func TestRunLSPExecuteCommandNotesList(t *testing.T) {
+	dir := t.TempDir()
+	js, err := newJSONStore(dir, GroupConfig{})
+	if err != nil {
+		t.Fatalf("newJSONStore: %v", err)
+	}
+	if err := js.Save(Note{ID: 1, Title: "Findme", Body: "hello world"}); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	var reqs bytes.Buffer
+	reqs.Write(frameRPC(t, "initialize", 1, map[string]interface{}{}))
+	reqs.Write(frameRPC(t, "workspace/executeCommand", 2, map[string]interface{}{
+		"command":   "notes.list",
+		"arguments": []interface{}{map[string]interface{}{"query": "hello"}},
+	}))
+
+	var out bytes.Buffer
+	if err := runLSP(&reqs, &out, &Notebook{Dir: dir}); err != nil {
+		t.Fatalf("runLSP: %v", err)
+	}
+	replies := decodeFramedReplies(t, out.Bytes())
+	if len(replies) != 2 {
+		t.Fatalf("got %d replies, want 2", len(replies))
+	}
+	hits, ok := replies[1].Result.([]interface{})
+	if !ok || len(hits) != 1 {
+		t.Fatalf("notes.list result = %#v, want one hit", replies[1].Result)
+	}
+}
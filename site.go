@@ -0,0 +1,112 @@
+// site.go
+// exportSite renders a whole notebook as a static site: one page per note,
+// one page per tag, and an index linking both, reusing Exporter for the
+// per-note HTML and TagsList-shaped grouping for the tag pages.
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+const siteIndexTemplate = `<!doctype html>
+<html>
+<head><meta charset="utf-8"><title>Notes</title></head>
+<body>
+<h1>Notes</h1>
+<ul>
+{{range .Notes}}<li><a href="{{.ID}}.html">{{.Title}}</a></li>
+{{end}}</ul>
+<h2>Tags</h2>
+<ul>
+{{range .Tags}}<li><a href="tag-{{.Slug}}.html">{{.Tag}}</a> ({{.Count}})</li>
+{{end}}</ul>
+</body>
+</html>
+`
+
+const siteTagTemplate = `<!doctype html>
+<html>
+<head><meta charset="utf-8"><title>Tag: {{.Tag}}</title></head>
+<body>
+<h1>Tag: {{.Tag}}</h1>
+<ul>
+{{range .Notes}}<li><a href="{{.ID}}.html">{{.Title}}</a></li>
+{{end}}</ul>
+<p><a href="index.html">&larr; all notes</a></p>
+</body>
+</html>
+`
+
+// siteTag pairs a tag with the slug used for its page filename/href, so a
+// tag containing "/" or spaces (e.g. "work/urgent") doesn't write outside
+// outDir or produce an href the index can't follow.
+type siteTag struct {
+	Tag   string
+	Slug  string
+	Count int
+}
+
+// exportSite writes outDir/<id>.html for every note, outDir/tag-<slug>.html
+// for every tag, and outDir/index.html linking them. It forces the HTML
+// format regardless of exporter.Format, since a static site only makes
+// sense rendered as pages.
+func exportSite(exporter *Exporter, notes []Note, outDir string) (int, error) {
+	if err := os.MkdirAll(outDir, 0o755); err != nil {
+		return 0, err
+	}
+	siteExporter := *exporter
+	siteExporter.Format = "html"
+
+	for _, n := range notes {
+		if err := siteExporter.ExportTo(n, filepath.Join(outDir, fmt.Sprintf("%d.html", n.ID))); err != nil {
+			return 0, fmt.Errorf("export note #%d: %w", n.ID, err)
+		}
+	}
+
+	tagged := map[string][]Note{}
+	for _, n := range notes {
+		for _, t := range n.Tags {
+			tagged[t] = append(tagged[t], n)
+		}
+	}
+	tags := make([]siteTag, 0, len(tagged))
+	for t, ns := range tagged {
+		tags = append(tags, siteTag{Tag: t, Slug: slugify(t), Count: len(ns)})
+	}
+	sort.Slice(tags, func(i, j int) bool { return tags[i].Tag < tags[j].Tag })
+
+	for _, tc := range tags {
+		if err := renderSitePage(siteTagTemplate, filepath.Join(outDir, "tag-"+tc.Slug+".html"), map[string]interface{}{
+			"Tag":   tc.Tag,
+			"Notes": tagged[tc.Tag],
+		}); err != nil {
+			return 0, err
+		}
+	}
+
+	if err := renderSitePage(siteIndexTemplate, filepath.Join(outDir, "index.html"), map[string]interface{}{
+		"Notes": notes,
+		"Tags":  tags,
+	}); err != nil {
+		return 0, err
+	}
+	return len(notes), nil
+}
+
+func renderSitePage(tplSrc, outPath string, data interface{}) error {
+	tpl, err := template.New("page").Parse(tplSrc)
+	if err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	if err := tpl.Execute(&buf, data); err != nil {
+		return err
+	}
+	return os.WriteFile(outPath, buf.Bytes(), 0o644)
+}
@@ -0,0 +1,26 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// TestExportSiteSlugifiesTagFilenames guards against a tag like
+// "work/urgent" writing outside outDir (or producing an href the index
+// can't follow) the way the raw tag name used to.
+func TestExportSiteSlugifiesTagFilenames(t *testing.T) {
+	dir := t.TempDir()
+	notes := []Note{{ID: 1, Title: "N", Body: "body", Tags: []string{"work/urgent"}, Created: time.Time{}}}
+	if _, err := exportSite(newExporter("html", ""), notes, dir); err != nil {
+		t.Fatalf("exportSite: %v", err)
+	}
+	want := filepath.Join(dir, "tag-work-urgent.html")
+	if _, err := os.Stat(want); err != nil {
+		t.Errorf("expected tag page at %s: %v", want, err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "work")); err == nil {
+		t.Errorf("tag slug must not create a subdirectory named after the unslugified tag")
+	}
+}
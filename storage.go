@@ -0,0 +1,82 @@
+// storage.go
+// Storage abstraction shared by the JSON-per-file backend and the
+// SQLite/FTS5-backed backend, so cmdSearch and friends don't care which
+// one is active.
+
+package main
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrNoteNotFound is returned by Load when no note exists with the given
+// id, so callers like resolveWikiLink can tell "unresolved link" apart
+// from a real I/O or database error.
+var ErrNoteNotFound = errors.New("note not found")
+
+type Note struct {
+	ID      int       `json:"id"`
+	Title   string    `json:"title"`
+	Body    string    `json:"body"`
+	Tags    []string  `json:"tags"`
+	Created time.Time `json:"created"`
+}
+
+// SearchFilters narrows a Search call beyond the free-text query string.
+// Zero values mean "no constraint".
+type SearchFilters struct {
+	Tag           string
+	CreatedAfter  time.Time
+	CreatedBefore time.Time
+}
+
+// SearchHit is one ranked search result, with a snippet highlighting the
+// matched terms (sqliteStore fills Snippet from FTS5's snippet(); jsonStore
+// fills it with the matching line).
+type SearchHit struct {
+	Note    Note
+	Snippet string
+}
+
+// TagCount pairs a tag with the number of notes it appears on.
+type TagCount struct {
+	Tag   string
+	Count int
+}
+
+// Storage is the persistence + search surface every CLI command runs
+// against. jsonStore is the original one-file-per-note layout; sqliteStore
+// layers a FTS5 index on top of its own SQLite-native tables. `notes
+// reindex` rebuilds a sqliteStore from a jsonStore so the two stay in
+// sync when a notebook switches backends.
+type Storage interface {
+	Load(id int) (*Note, error)
+	Save(n Note) error
+	All() ([]Note, error)
+	Search(query string, filters SearchFilters) ([]SearchHit, error)
+	TagsList() ([]TagCount, error)
+	Close() error
+}
+
+func matchesFilters(n Note, f SearchFilters) bool {
+	if f.Tag != "" {
+		found := false
+		for _, t := range n.Tags {
+			if t == f.Tag {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if !f.CreatedAfter.IsZero() && n.Created.Before(f.CreatedAfter) {
+		return false
+	}
+	if !f.CreatedBefore.IsZero() && n.Created.After(f.CreatedBefore) {
+		return false
+	}
+	return true
+}
@@ -0,0 +1,165 @@
+// storage_json.go
+// jsonStore is the original one-JSON-file-per-note backend
+// (notes_db/0001.json, notes_db/0002.json, ...). Search is a plain
+// substring scan; it has no ranking or snippets beyond the matching line.
+//
+// Every file on disk passes through a noteCipher (see crypto.go): a
+// plainCipher for ordinary notebooks, or an ageCipher once the notebook
+// was `init --encrypt`-ed. jsonStore itself never knows which.
+//
+// File names are derived from the notebook's GroupConfig (see config.go),
+// defaulting to the historical zero-padded "0001.json" scheme.
+
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+type jsonStore struct {
+	dir    string
+	group  GroupConfig
+	cipher noteCipher
+}
+
+func newJSONStore(dir string, group GroupConfig) (*jsonStore, error) {
+	if err := group.validate(dir); err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	cipher, err := notebookCipher(dir)
+	if err != nil {
+		return nil, err
+	}
+	return &jsonStore{dir: dir, group: group, cipher: cipher}, nil
+}
+
+func (s *jsonStore) notePath(id int) string {
+	return filepath.Join(s.dir, s.group.filename(id))
+}
+
+func (s *jsonStore) All() ([]Note, error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, err
+	}
+	ext := "." + s.group.extOrDefault()
+	notes := make([]Note, 0, len(entries))
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), ext) {
+			continue
+		}
+		b, err := os.ReadFile(filepath.Join(s.dir, e.Name()))
+		if err != nil {
+			continue
+		}
+		plain, err := s.cipher.Decrypt(b)
+		if err != nil {
+			continue
+		}
+		var n Note
+		if err := json.Unmarshal(plain, &n); err == nil {
+			notes = append(notes, n)
+		}
+	}
+	sort.Slice(notes, func(i, j int) bool { return notes[i].ID < notes[j].ID })
+	return notes, nil
+}
+
+func (s *jsonStore) Load(id int) (*Note, error) {
+	b, err := os.ReadFile(s.notePath(id))
+	if os.IsNotExist(err) {
+		return nil, ErrNoteNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	plain, err := s.cipher.Decrypt(b)
+	if err != nil {
+		return nil, err
+	}
+	var n Note
+	if err := json.Unmarshal(plain, &n); err != nil {
+		return nil, err
+	}
+	return &n, nil
+}
+
+func (s *jsonStore) Save(n Note) error {
+	plain, err := json.MarshalIndent(n, "", "  ")
+	if err != nil {
+		return err
+	}
+	b, err := s.cipher.Encrypt(plain)
+	if err != nil {
+		return err
+	}
+	tmp := s.notePath(n.ID) + ".tmp"
+	if err := os.WriteFile(tmp, b, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.notePath(n.ID))
+}
+
+func (s *jsonStore) Search(query string, filters SearchFilters) ([]SearchHit, error) {
+	notes, err := s.All()
+	if err != nil {
+		return nil, err
+	}
+	q := strings.ToLower(query)
+	var hits []SearchHit
+	for _, n := range notes {
+		if !matchesFilters(n, filters) {
+			continue
+		}
+		if q != "" &&
+			!strings.Contains(strings.ToLower(n.Title), q) &&
+			!strings.Contains(strings.ToLower(n.Body), q) &&
+			!strings.Contains(strings.ToLower(strings.Join(n.Tags, ",")), q) {
+			continue
+		}
+		hits = append(hits, SearchHit{Note: n, Snippet: firstMatchingLine(n.Body, q)})
+	}
+	return hits, nil
+}
+
+// firstMatchingLine returns the first body line containing q, used as a
+// cheap stand-in for sqliteStore's FTS5 snippet() output.
+func firstMatchingLine(body, q string) string {
+	if q == "" {
+		return ""
+	}
+	for _, line := range strings.Split(body, "\n") {
+		if strings.Contains(strings.ToLower(line), q) {
+			return strings.TrimSpace(line)
+		}
+	}
+	return ""
+}
+
+func (s *jsonStore) TagsList() ([]TagCount, error) {
+	notes, err := s.All()
+	if err != nil {
+		return nil, err
+	}
+	counts := map[string]int{}
+	for _, n := range notes {
+		for _, t := range n.Tags {
+			counts[t]++
+		}
+	}
+	out := make([]TagCount, 0, len(counts))
+	for t, c := range counts {
+		out = append(out, TagCount{Tag: t, Count: c})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Tag < out[j].Tag })
+	return out, nil
+}
+
+func (s *jsonStore) Close() error { return nil }
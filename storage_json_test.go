@@ -0,0 +1,47 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+// TestNewJSONStoreRejectsPathTraversalPattern guards against a notebook's
+// own .notes/config.toml (trusted by resolveNotebook for any bare `notes`
+// invocation in that directory) using group.pattern/extension to write
+// note files outside the notebook dir.
+func TestNewJSONStoreRejectsPathTraversalPattern(t *testing.T) {
+	dir := t.TempDir()
+	cases := []GroupConfig{
+		{Pattern: "../../../tmp/pwned-{id}.{ext}"},
+		{Pattern: "{ext}", Extension: "../../escape"},
+	}
+	for _, g := range cases {
+		if _, err := newJSONStore(dir, g); err == nil {
+			t.Errorf("newJSONStore(%+v) succeeded, want an error for an escaping pattern", g)
+		}
+	}
+}
+
+func TestNewJSONStoreAllowsOrdinaryPatterns(t *testing.T) {
+	dir := t.TempDir()
+	cases := []GroupConfig{
+		{},
+		{Pattern: "note-{id}.{ext}"},
+		{Pattern: "sub/dir/{id}.{ext}"}, // a subdirectory inside dir is fine
+	}
+	for _, g := range cases {
+		if _, err := newJSONStore(dir, g); err != nil {
+			t.Errorf("newJSONStore(%+v) = %v, want success", g, err)
+		}
+	}
+}
+
+func TestGroupConfigValidateRel(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "notebook")
+	if err := (GroupConfig{Pattern: "{id}.{ext}"}).validate(dir); err != nil {
+		t.Errorf("validate of the default pattern failed: %v", err)
+	}
+	if err := (GroupConfig{Pattern: "../sibling-{id}.{ext}"}).validate(dir); err == nil {
+		t.Error("validate should reject a pattern landing in a sibling directory")
+	}
+}
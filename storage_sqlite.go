@@ -0,0 +1,260 @@
+// storage_sqlite.go
+// sqliteStore keeps notes in a SQLite database (notes_db/notes.db) with a
+// companion FTS5 virtual table for ranked full-text search over title,
+// body and tags. `notes reindex` rebuilds this file from a jsonStore's
+// *.json files, so a notebook can move between backends without losing
+// data.
+//
+// mattn/go-sqlite3 only compiles in its FTS5 module when built with the
+// sqlite_fts5 build tag (`go build -tags sqlite_fts5 .`, or `make build`);
+// without it, newSQLiteStore's CREATE VIRTUAL TABLE fails with "no such
+// module: fts5".
+
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+const sqliteSchema = `
+CREATE TABLE IF NOT EXISTS notes (
+	id      INTEGER PRIMARY KEY,
+	title   TEXT NOT NULL,
+	body    TEXT NOT NULL,
+	tags    TEXT NOT NULL DEFAULT '',
+	created DATETIME NOT NULL
+);
+CREATE VIRTUAL TABLE IF NOT EXISTS notes_fts USING fts5(
+	title, body, tags, content='notes', content_rowid='id'
+);
+CREATE TRIGGER IF NOT EXISTS notes_ai AFTER INSERT ON notes BEGIN
+	INSERT INTO notes_fts(rowid, title, body, tags) VALUES (new.id, new.title, new.body, new.tags);
+END;
+CREATE TRIGGER IF NOT EXISTS notes_ad AFTER DELETE ON notes BEGIN
+	INSERT INTO notes_fts(notes_fts, rowid, title, body, tags) VALUES ('delete', old.id, old.title, old.body, old.tags);
+END;
+CREATE TRIGGER IF NOT EXISTS notes_au AFTER UPDATE ON notes BEGIN
+	INSERT INTO notes_fts(notes_fts, rowid, title, body, tags) VALUES ('delete', old.id, old.title, old.body, old.tags);
+	INSERT INTO notes_fts(rowid, title, body, tags) VALUES (new.id, new.title, new.body, new.tags);
+END;
+`
+
+type sqliteStore struct {
+	db *sql.DB
+}
+
+func newSQLiteStore(path string) (*sqliteStore, error) {
+	db, err := sql.Open("sqlite3", path+"?_foreign_keys=on")
+	if err != nil {
+		return nil, err
+	}
+	if _, err := db.Exec(sqliteSchema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("migrate schema: %w", err)
+	}
+	return &sqliteStore{db: db}, nil
+}
+
+func (s *sqliteStore) Close() error { return s.db.Close() }
+
+func scanNote(row interface{ Scan(...any) error }) (Note, error) {
+	var n Note
+	var tags string
+	if err := row.Scan(&n.ID, &n.Title, &n.Body, &tags, &n.Created); err != nil {
+		return Note{}, err
+	}
+	n.Tags = splitTags(tags)
+	return n, nil
+}
+
+func splitTags(s string) []string {
+	if s == "" {
+		return []string{}
+	}
+	return strings.Split(s, ",")
+}
+
+func joinTags(tags []string) string {
+	return strings.Join(tags, ",")
+}
+
+func (s *sqliteStore) Load(id int) (*Note, error) {
+	row := s.db.QueryRow(`SELECT id, title, body, tags, created FROM notes WHERE id = ?`, id)
+	n, err := scanNote(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNoteNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &n, nil
+}
+
+func (s *sqliteStore) Save(n Note) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	_, err = tx.Exec(`
+		INSERT INTO notes (id, title, body, tags, created) VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT(id) DO UPDATE SET title = excluded.title, body = excluded.body,
+			tags = excluded.tags, created = excluded.created
+	`, n.ID, n.Title, n.Body, joinTags(n.Tags), n.Created)
+	if err != nil {
+		return fmt.Errorf("save note #%d: %w", n.ID, err)
+	}
+	return tx.Commit()
+}
+
+func (s *sqliteStore) All() ([]Note, error) {
+	rows, err := s.db.Query(`SELECT id, title, body, tags, created FROM notes ORDER BY id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var notes []Note
+	for rows.Next() {
+		n, err := scanNote(rows)
+		if err != nil {
+			return nil, err
+		}
+		notes = append(notes, n)
+	}
+	return notes, rows.Err()
+}
+
+// ftsPhrase quotes s as an FTS5 string literal (doubling embedded quotes),
+// so punctuation FTS5 would otherwise read as column/operator syntax
+// ("-", ":", "*", unbalanced '"') is matched as literal text instead of
+// erroring or silently changing the query, matching jsonStore's plain
+// substring semantics.
+func ftsPhrase(s string) string {
+	return `"` + strings.ReplaceAll(s, `"`, `""`) + `"`
+}
+
+// ftsPhraseQuery turns free text into a safe FTS5 MATCH expression: every
+// whitespace-separated term becomes its own quoted phrase, ANDed together
+// (FTS5's default) by the space between them.
+func ftsPhraseQuery(query string) string {
+	fields := strings.Fields(query)
+	terms := make([]string, len(fields))
+	for i, f := range fields {
+		terms[i] = ftsPhrase(f)
+	}
+	return strings.Join(terms, " ")
+}
+
+// Search runs a ranked FTS5 MATCH query when there's free text to search
+// for. filters.Tag is applied as an extra `tags:` column filter; date
+// filters are applied on the non-indexed `created` column after the
+// MATCH narrows the candidate set. An empty free-text query has nothing
+// for MATCH to rank, so it bypasses FTS entirely and filters the notes
+// table directly — an empty MATCH string matches nothing, which would
+// otherwise turn e.g. `notes search created:2024-01-01..2024-12-31` into
+// a silent "no results" on this backend only.
+func (s *sqliteStore) Search(query string, filters SearchFilters) ([]SearchHit, error) {
+	query = strings.TrimSpace(query)
+	if query == "" {
+		return s.searchAll(filters)
+	}
+	match := ftsPhraseQuery(query)
+	if filters.Tag != "" {
+		match = fmt.Sprintf(`%s tags:%s`, match, ftsPhrase(filters.Tag))
+	}
+	rows, err := s.db.Query(`
+		SELECT n.id, n.title, n.body, n.tags, n.created,
+		       snippet(notes_fts, 1, '[', ']', '...', 8)
+		FROM notes_fts
+		JOIN notes n ON n.id = notes_fts.rowid
+		WHERE notes_fts MATCH ?
+		ORDER BY rank
+	`, match)
+	if err != nil {
+		return nil, fmt.Errorf("fts query %q: %w", match, err)
+	}
+	defer rows.Close()
+	var hits []SearchHit
+	for rows.Next() {
+		var n Note
+		var tags, snippet string
+		if err := rows.Scan(&n.ID, &n.Title, &n.Body, &tags, &n.Created, &snippet); err != nil {
+			return nil, err
+		}
+		n.Tags = splitTags(tags)
+		if !matchesFilters(n, filters) {
+			continue
+		}
+		hits = append(hits, SearchHit{Note: n, Snippet: snippet})
+	}
+	return hits, rows.Err()
+}
+
+// searchAll applies filters over every note without going through FTS,
+// for queries with no free text to match/rank.
+func (s *sqliteStore) searchAll(filters SearchFilters) ([]SearchHit, error) {
+	notes, err := s.All()
+	if err != nil {
+		return nil, err
+	}
+	var hits []SearchHit
+	for _, n := range notes {
+		if !matchesFilters(n, filters) {
+			continue
+		}
+		hits = append(hits, SearchHit{Note: n})
+	}
+	return hits, nil
+}
+
+func (s *sqliteStore) TagsList() ([]TagCount, error) {
+	notes, err := s.All()
+	if err != nil {
+		return nil, err
+	}
+	counts := map[string]int{}
+	for _, n := range notes {
+		for _, t := range n.Tags {
+			counts[t]++
+		}
+	}
+	out := make([]TagCount, 0, len(counts))
+	for t, c := range counts {
+		out = append(out, TagCount{Tag: t, Count: c})
+	}
+	return out, nil
+}
+
+// reindexFromJSON rebuilds the SQLite store's notes/notes_fts tables from a
+// jsonStore's files, so `notes reindex` can recover from drift between the
+// two backends.
+func reindexFromJSON(js *jsonStore, sq *sqliteStore) (int, error) {
+	notes, err := js.All()
+	if err != nil {
+		return 0, err
+	}
+	tx, err := sq.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+	if _, err := tx.Exec(`DELETE FROM notes`); err != nil {
+		return 0, err
+	}
+	for _, n := range notes {
+		_, err := tx.Exec(`INSERT INTO notes (id, title, body, tags, created) VALUES (?, ?, ?, ?, ?)`,
+			n.ID, n.Title, n.Body, joinTags(n.Tags), n.Created)
+		if err != nil {
+			return 0, fmt.Errorf("reindex note #%d: %w", n.ID, err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return len(notes), nil
+}
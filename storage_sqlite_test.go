@@ -0,0 +1,27 @@
+package main
+
+import "testing"
+
+func TestFtsPhraseEscapesQuotes(t *testing.T) {
+	got := ftsPhrase(`say "hi"`)
+	want := `"say ""hi"""`
+	if got != want {
+		t.Errorf("ftsPhrase(%q) = %q, want %q", `say "hi"`, got, want)
+	}
+}
+
+func TestFtsPhraseQueryTreatsFts5SyntaxAsLiteral(t *testing.T) {
+	// "-", ":", "*" are FTS5 operators; ftsPhraseQuery must quote each
+	// term so they're matched as literal text instead of being parsed.
+	got := ftsPhraseQuery(`foo-bar tag:urgent wild*`)
+	want := `"foo-bar" "tag:urgent" "wild*"`
+	if got != want {
+		t.Errorf("ftsPhraseQuery(...) = %q, want %q", got, want)
+	}
+}
+
+func TestFtsPhraseQueryEmpty(t *testing.T) {
+	if got := ftsPhraseQuery(""); got != "" {
+		t.Errorf("ftsPhraseQuery(\"\") = %q, want empty string", got)
+	}
+}